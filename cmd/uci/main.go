@@ -0,0 +1,16 @@
+// Command uci runs the engine as a UCI engine on stdin and stdout
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/LIAMBB/chess-compute/uci"
+)
+
+func main() {
+	if err := uci.Run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading input:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,16 @@
+// Command xboard runs the engine as an XBoard (CECP) engine on stdin and stdout
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/LIAMBB/chess-compute/xboard"
+)
+
+func main() {
+	if err := xboard.Run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "Error reading input:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,453 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/search"
+	"github.com/LIAMBB/chess-compute/tablebase"
+)
+
+// runCommand dispatches the subcommands. Only those reading the explored tree
+// open chess.db, so bench, tablebase and mate on a FEN leave no file behind.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "bench":
+		return runBench(args)
+	case "tablebase":
+		return runTablebase(args)
+	case "mate":
+		return runMate(args)
+	case "minimax", "browse", "search", "perft", "mcts", "prove", "label":
+	default:
+		return fmt.Errorf("unknown command %q (expected minimax, browse, search, perft, bench, mcts, tablebase, prove, mate or label)", name)
+	}
+
+	db, err := openDatabase()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch name {
+	case "minimax":
+		rootID, err := rootIDFromArgs(db, args)
+		if err != nil {
+			return err
+		}
+		board, err := NewSQLiteStore(db).Position(rootID)
+		if err != nil {
+			return err
+		}
+		return backPropagateMinimax(db, rootID, components.VariantOf(board))
+	case "browse":
+		rootID, err := rootIDFromArgs(db, args)
+		if err != nil {
+			return err
+		}
+		store := NewSQLiteStore(db)
+		board, err := store.Position(rootID)
+		if err != nil {
+			return err
+		}
+		traverseTree(&BoardRouteNode{StateID: rootID, NextStateIDs: []int{}}, store, components.VariantOf(board))
+		return nil
+	case "search":
+		return runSearch(db, args)
+	case "perft":
+		return runPerft(db, args)
+	case "mcts":
+		return runMCTS(db, args)
+	case "prove":
+		return runProve(db, args)
+	default: // label
+		return runLabel(db)
+	}
+}
+
+func openDatabase() (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "./chess.db")
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %v", err)
+	}
+
+	_, err = db.Exec(`
+		PRAGMA journal_mode=WAL;
+		PRAGMA synchronous=NORMAL;
+		PRAGMA cache_size=10000;
+		PRAGMA temp_store=MEMORY;
+	`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error setting SQLite pragmas: %v", err)
+	}
+
+	if err := createSchema(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating table: %v", err)
+	}
+	return db, nil
+}
+
+// rootIDFromArgs reads an optional node ID argument, defaulting to the first
+// stored board state which is the root of the first exploration run
+func rootIDFromArgs(db *sql.DB, args []string) (int, error) {
+	if len(args) > 0 {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return 0, fmt.Errorf("invalid node ID %q", args[0])
+		}
+		return id, nil
+	}
+
+	var rootID sql.NullInt64
+	if err := db.QueryRow("SELECT MIN(id) FROM board_states").Scan(&rootID); err != nil {
+		return 0, fmt.Errorf("error finding root state: %v", err)
+	}
+	if !rootID.Valid {
+		return 0, fmt.Errorf("chess.db contains no board states")
+	}
+	return int(rootID.Int64), nil
+}
+
+// runSearch searches a stored position: search [nodeID] [depth]. Ctrl-C stops
+// the search and prints the best move of the last completed iteration.
+func runSearch(db *sql.DB, args []string) error {
+	rootID, err := rootIDFromArgs(db, args)
+	if err != nil {
+		return err
+	}
+	limits := search.Limits{}
+	if len(args) > 1 {
+		if limits.Depth, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("invalid depth %q", args[1])
+		}
+	}
+
+	board, err := NewSQLiteStore(db).Position(rootID)
+	if err != nil {
+		return err
+	}
+	board.ToString()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	engine := search.NewEngine()
+	engine.Variant = components.VariantOf(board)
+	engine.Tablebases = tablebases
+	engine.OnIteration = func(info search.Info) {
+		fmt.Printf("depth %d score %s nodes %d time %s pv %s\n",
+			info.Depth, components.FormatScore(info.Score), info.Nodes,
+			info.Elapsed.Round(time.Millisecond), formatPV(info.PV))
+	}
+	result := engine.Search(ctx, *board, limits)
+	fmt.Printf("Best move: %s (%s)\n", result.BestMove.UCI(), components.FormatScore(result.Score))
+	return nil
+}
+
+// runPerft counts legal move paths from a stored position: perft [nodeID]
+// [depth]. Counts per root move are printed to help track down move
+// generation bugs.
+func runPerft(db *sql.DB, args []string) error {
+	rootID, err := rootIDFromArgs(db, args)
+	if err != nil {
+		return err
+	}
+	depth := 4
+	if len(args) > 1 {
+		if depth, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("invalid depth %q", args[1])
+		}
+	}
+
+	board, err := NewSQLiteStore(db).Position(rootID)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	var total uint64
+	for _, result := range search.PerftDivide(*board, depth, search.NewTranspositionTable(search.DefaultHashMB)) {
+		fmt.Printf("%s: %d\n", result.Move.UCI(), result.Nodes)
+		total += result.Nodes
+	}
+	fmt.Printf("\nNodes searched: %d (%s)\n", total, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// runMate solves a mate-in-N problem: mate [nodeID|FEN] [N]. It lists every
+// key move forcing mate in at most N moves with its fastest mate, and the
+// refutation of every other move.
+func runMate(args []string) error {
+	var board *components.ChessBoard
+	if len(args) > 0 && strings.Contains(args[0], "/") {
+		parsed, err := components.ParseFEN(args[0])
+		if err != nil {
+			return err
+		}
+		board = &parsed
+	} else {
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		rootID, err := rootIDFromArgs(db, args)
+		if err != nil {
+			return err
+		}
+		if board, err = NewSQLiteStore(db).Position(rootID); err != nil {
+			return err
+		}
+	}
+	if _, ok := components.VariantOf(board).(components.Standard); !ok {
+		return fmt.Errorf("the mate solver only supports standard chess")
+	}
+	moves := 2
+	if len(args) > 1 {
+		var err error
+		if moves, err = strconv.Atoi(args[1]); err != nil || moves < 1 {
+			return fmt.Errorf("invalid number of moves %q", args[1])
+		}
+	}
+	board.ToString()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	solution := search.SolveMate(ctx, *board, moves, search.NewTranspositionTable(search.DefaultHashMB))
+	if ctx.Err() != nil {
+		fmt.Println("Interrupted, the results below are incomplete")
+	}
+
+	keys := solution.Keys()
+	if len(keys) == 0 {
+		fmt.Printf("No mate in %d\n", moves)
+	}
+	for _, key := range keys {
+		fmt.Printf("Key %s: mate in %d\n", key.Move.UCI(), key.MateIn)
+	}
+	for _, try := range solution.Tries() {
+		if try.Stalemate {
+			fmt.Printf("Try %s: stalemate\n", try.Move.UCI())
+		} else {
+			fmt.Printf("Try %s: refuted by %s\n", try.Move.UCI(), try.Refutation.UCI())
+		}
+	}
+	fmt.Printf("\nNodes searched: %d (%s)\n", solution.Nodes, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// benchPositions cover the opening, a tactical middlegame and an endgame
+var benchPositions = []string{
+	components.StartFEN,
+	"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+	"r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4",
+	"r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10",
+	"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+}
+
+// runBench searches the bench positions to a fixed depth with and without
+// move ordering and reports the nodes each needed: bench [depth]
+func runBench(args []string) error {
+	depth := 4
+	if len(args) > 0 {
+		var err error
+		if depth, err = strconv.Atoi(args[0]); err != nil {
+			return fmt.Errorf("invalid depth %q", args[0])
+		}
+	}
+
+	var totalPlain, totalOrdered uint64
+	for _, fen := range benchPositions {
+		board, err := components.ParseFEN(fen)
+		if err != nil {
+			return err
+		}
+
+		plain, plainTime := benchSearch(board, depth, false)
+		ordered, orderedTime := benchSearch(board, depth, true)
+		totalPlain += plain
+		totalOrdered += ordered
+		fmt.Printf("%s\n  unordered: %d nodes (%s)  ordered: %d nodes (%s)\n",
+			fen, plain, plainTime.Round(time.Millisecond), ordered, orderedTime.Round(time.Millisecond))
+	}
+
+	fmt.Printf("\nTotal at depth %d: unordered %d nodes, ordered %d nodes (%.1f%% fewer)\n",
+		depth, totalPlain, totalOrdered, 100*(1-float64(totalOrdered)/float64(totalPlain)))
+	return nil
+}
+
+func benchSearch(board components.ChessBoard, depth int, ordering bool) (uint64, time.Duration) {
+	engine := search.NewEngine()
+	engine.MoveOrdering = ordering
+	start := time.Now()
+	result := engine.Search(context.Background(), board, search.Limits{Depth: depth})
+	return result.Nodes, time.Since(start)
+}
+
+// Directory the tablebase command writes tables to by default
+const defaultTablebaseDir = "./tablebases"
+
+// tablebases are probed by exploration, minimax, MCTS, search and the
+// browser. Tables that were not generated into defaultTablebaseDir are
+// simply not used.
+var tablebases = tablebase.NewSet(defaultTablebaseDir)
+
+// runTablebase generates endgame tables or checks the generator:
+// tablebase generate [dir] [table...] writes the named tables, or all tables
+// with up to four pieces, and tablebase verify compares the longest mates of
+// freshly generated tables with their published values
+func runTablebase(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: tablebase generate [dir] [table...] | tablebase verify")
+	}
+
+	switch args[0] {
+	case "generate":
+		dir := defaultTablebaseDir
+		if len(args) > 1 {
+			dir = args[1]
+		}
+		names := tablebase.AllNames()
+		if len(args) > 2 {
+			names = args[2:]
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("error creating tablebase directory: %v", err)
+		}
+
+		set := tablebase.NewSet(dir)
+		for _, name := range names {
+			start := time.Now()
+			table, err := set.Table(name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%-7s longest %s (%s)\n", table.Name, table.LongestWin(), time.Since(start).Round(time.Millisecond))
+		}
+		return nil
+	case "verify":
+		return tablebase.NewSet("").Verify(func(name string, want, got int) {
+			status := "ok"
+			if want != got {
+				status = "MISMATCH"
+			}
+			fmt.Printf("%-7s longest mate %d, expected %d: %s\n", name, got, want, status)
+		})
+	default:
+		return fmt.Errorf("unknown tablebase command %q (expected generate or verify)", args[0])
+	}
+}
+
+// runLabel fills in the move columns of edges stored before they existed.
+// Each parent's pseudo-legal moves are matched to its children by position
+// hash, like exploration generated them. Edges no move reaches keep no label.
+func runLabel(db *sql.DB) error {
+	var missing int
+	if err := db.QueryRow("SELECT COUNT(*) FROM node_relations WHERE move_uci IS NULL").Scan(&missing); err != nil {
+		return fmt.Errorf("error counting unlabeled edges: %v", err)
+	}
+	fmt.Printf("Labeling %d edges\n", missing)
+
+	store := NewSQLiteStore(db)
+	labeled, lastParent := 0, 0
+	for {
+		parentIDs, err := store.queryIDs(`
+			SELECT DISTINCT parent_id
+			FROM node_relations
+			WHERE move_uci IS NULL AND parent_id > ?
+			ORDER BY parent_id
+			LIMIT 1000
+		`, lastParent)
+		if err != nil {
+			return err
+		}
+		if len(parentIDs) == 0 {
+			break
+		}
+		lastParent = parentIDs[len(parentIDs)-1]
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("error starting transaction: %v", err)
+		}
+		for _, parentID := range parentIDs {
+			n, err := labelEdges(tx, store, parentID)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			labeled += n
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("error committing labels: %v", err)
+		}
+		fmt.Printf("Labeled %d of %d edges\n", labeled, missing)
+	}
+
+	if labeled < missing {
+		fmt.Printf("%d edges match no move of their parent and were left unlabeled\n", missing-labeled)
+	}
+	return nil
+}
+
+// labelEdges labels the unlabeled edges from one parent within tx, returning
+// how many it labeled
+func labelEdges(tx *sql.Tx, store *SQLiteStore, parentID int) (int, error) {
+	board, err := store.Position(parentID)
+	if err != nil {
+		return 0, err
+	}
+	childIDs, err := store.queryIDs("SELECT child_id FROM node_relations WHERE parent_id = ? AND move_uci IS NULL", parentID)
+	if err != nil {
+		return 0, err
+	}
+
+	moveByHash := make(map[uint64]components.Move)
+	for _, child := range components.VariantOf(board).PseudoLegalMoves(board, false) {
+		moveByHash[child.Hash()] = child.LastMove
+	}
+	sans := board.SANs()
+
+	labeled := 0
+	for _, childID := range childIDs {
+		childBoard, err := store.Position(childID)
+		if err != nil {
+			return labeled, err
+		}
+		move, ok := moveByHash[childBoard.Hash()]
+		if !ok {
+			continue
+		}
+		label := newMoveLabel(board, sans, move)
+		_, err = tx.Exec(`
+			UPDATE node_relations
+			SET move_from = ?, move_to = ?, move_promotion = ?, move_flags = ?, move_san = ?, move_uci = ?
+			WHERE parent_id = ? AND child_id = ?`,
+			append(label.columns(), parentID, childID)...)
+		if err != nil {
+			return labeled, fmt.Errorf("error labeling edge %d -> %d: %v", parentID, childID, err)
+		}
+		labeled++
+	}
+	return labeled, nil
+}
+
+func formatPV(pv []components.Move) string {
+	moves := make([]string, len(pv))
+	for i, move := range pv {
+		moves[i] = move.UCI()
+	}
+	return strings.Join(moves, " ")
+}
@@ -0,0 +1,92 @@
+package components
+
+const antichessName = "antichess"
+
+// Antichess, or losing chess, is won by losing every piece or running out of
+// moves. Captures are compulsory, the king is an ordinary piece that can be
+// captured and never in check, pawns may also promote to a king, and there
+// is no castling.
+type Antichess struct{}
+
+func (Antichess) Name() string {
+	return antichessName
+}
+
+func (Antichess) StartPosition() ChessBoard {
+	board := Standard{}.StartPosition()
+	board.Variant = antichessName
+	board.Castling = CastlingRights{}
+	return board
+}
+
+// PseudoLegalMoves adds promotions to a king and keeps only the captures when
+// there are any. capturesOnly keeps promotions too when there are none, as
+// they are then the only moves quiescence search needs.
+func (Antichess) PseudoLegalMoves(board *ChessBoard, capturesOnly bool) []*ChessBoard {
+	children := board.PseudoLegalMoves(false)
+	for _, child := range children {
+		if child.LastMove.Promotion == 'Q' {
+			king := child.DeepCopy()
+			king.Board[king.LastMove.To.Y][king.LastMove.To.X] = NewPiece('K', board.NextTurn)
+			king.LastMove.Promotion = 'K'
+			children = append(children, king)
+		}
+	}
+
+	moves, forced := board.forcedCaptures(children)
+	if forced || !capturesOnly {
+		return moves
+	}
+	var promotions []*ChessBoard
+	for _, child := range children {
+		if child.LastMove.Promotion != 0 {
+			promotions = append(promotions, child)
+		}
+	}
+	return promotions
+}
+
+// IsLegal accepts every move, as there is no check
+func (Antichess) IsLegal(board *ChessBoard) bool {
+	return true
+}
+
+func (Antichess) InCheck(board *ChessBoard) bool {
+	return false
+}
+
+// Decided is a win for a side that has lost all its pieces
+func (Antichess) Decided(board *ChessBoard) Result {
+	for _, row := range board.Board {
+		for _, piece := range row {
+			if piece != nil && piece.GetColor() == board.NextTurn {
+				return Ongoing
+			}
+		}
+	}
+	return winFor(board.NextTurn)
+}
+
+// Result is a win for the side to move once it has no pieces or no moves
+func (Antichess) Result(board *ChessBoard, moves []*ChessBoard) Result {
+	if len(moves) > 0 {
+		return Ongoing
+	}
+	return winFor(board.NextTurn)
+}
+
+// forcedCaptures returns only the captures among the children of cb if there
+// is at least one, reporting whether captures were forced, and all children
+// otherwise
+func (cb *ChessBoard) forcedCaptures(children []*ChessBoard) ([]*ChessBoard, bool) {
+	var captures []*ChessBoard
+	for _, child := range children {
+		if cb.CapturedPiece(child.LastMove) != nil {
+			captures = append(captures, child)
+		}
+	}
+	if len(captures) == 0 {
+		return children, false
+	}
+	return captures, true
+}
@@ -0,0 +1,58 @@
+package components
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAntichessPerft(t *testing.T) {
+	board := Antichess{}.StartPosition()
+	for depth, want := range []int{1, 20, 400, 8067} {
+		if got := perft(Antichess{}, &board, depth); got != want {
+			t.Errorf("perft(%d) = %d, want %d", depth, got, want)
+		}
+	}
+}
+
+func TestAntichessMoves(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		fen  string
+		want []string
+	}{
+		// Both captures of the pawn are forced, even the king's
+		{"compulsory captures", "4k3/8/8/8/8/8/3p4/2B1K3 w - - 0 1", []string{"c1d2", "e1d2"}},
+		{"king promotion", "8/P7/8/8/8/8/8/k7 w - - 0 1", []string{"a7a8b", "a7a8k", "a7a8n", "a7a8q", "a7a8r"}},
+		// The king may walk into attack, there being no check
+		{"no check", "8/8/8/8/8/8/r7/4K3 w - - 0 1", []string{"e1d1", "e1d2", "e1e2", "e1f1", "e1f2"}},
+	} {
+		board := parseVariantFEN(t, test.fen, antichessName)
+		if got := moveNames(LegalMoves(Antichess{}, board)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: moves %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	board := parseVariantFEN(t, "8/P7/8/8/8/8/8/k7 w - - 0 1", antichessName)
+	king := playMove(t, Antichess{}, board, "a7a8k")
+	if piece := king.Board[7][0]; piece == nil || piece.ToString() != " W K " {
+		t.Errorf("a8 holds %v after promoting to a king", piece)
+	}
+}
+
+func TestAntichessResult(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		fen  string
+		want Result
+	}{
+		{"white lost every piece", "8/8/8/8/8/8/8/k7 w - - 0 1", WhiteWins},
+		{"black lost every piece", "8/8/8/8/8/8/8/K7 b - - 0 1", BlackWins},
+		{"white stalemated", "8/8/8/8/8/p7/P7/8 w - - 0 1", WhiteWins},
+		{"pieces and moves left", "8/8/8/8/8/8/P7/k7 w - - 0 1", Ongoing},
+	} {
+		board := parseVariantFEN(t, test.fen, antichessName)
+		if got := GameResult(Antichess{}, board); got != test.want {
+			t.Errorf("%s: result %s, want %s", test.name, got, test.want)
+		}
+	}
+}
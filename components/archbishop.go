@@ -0,0 +1,43 @@
+package components
+
+// Archbishop moves as a bishop and as a knight. It is one of the two compound pieces of
+// Capablanca chess.
+type Archbishop struct {
+	Color bool // white = true, black = false
+}
+
+func (archbishop Archbishop) GetPossibleMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard {
+	possibleBoards := Bishop{Color: archbishop.Color}.GetPossibleMoves(board, position, false)
+	possibleBoards = append(possibleBoards, Knight{Color: archbishop.Color}.GetPossibleMoves(board, position, false)...)
+
+	// Filter boards if inCheck is true
+	if inCheck {
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, archbishop.Color)
+	}
+
+	return possibleBoards
+}
+
+// GetCaptureMoves returns only the moves that take an enemy piece
+func (archbishop Archbishop) GetCaptureMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard {
+	possibleBoards := Bishop{Color: archbishop.Color}.GetCaptureMoves(board, position, false)
+	possibleBoards = append(possibleBoards, Knight{Color: archbishop.Color}.GetCaptureMoves(board, position, false)...)
+
+	if inCheck {
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, archbishop.Color)
+	}
+
+	return possibleBoards
+}
+
+func (archbishop Archbishop) GetColor() bool {
+	return archbishop.Color
+}
+
+func (archbishop Archbishop) ToString() string {
+	if archbishop.Color {
+		return " W A "
+	} else {
+		return " B A "
+	}
+}
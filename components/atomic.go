@@ -0,0 +1,151 @@
+package components
+
+const atomicName = "atomic"
+
+// Atomic is chess where every capture is an explosion: the capturing and the
+// captured piece are removed together with every piece other than a pawn
+// next to the capture square. Kings cannot capture, a move may not explode
+// the mover's own king, and exploding the enemy king wins. Kings standing
+// next to each other cannot give check, as taking one would explode both.
+type Atomic struct{}
+
+func (Atomic) Name() string {
+	return atomicName
+}
+
+func (Atomic) StartPosition() ChessBoard {
+	board := Standard{}.StartPosition()
+	board.Variant = atomicName
+	return board
+}
+
+// PseudoLegalMoves applies the explosion to every capture and drops king
+// captures
+func (Atomic) PseudoLegalMoves(board *ChessBoard, capturesOnly bool) []*ChessBoard {
+	var boards []*ChessBoard
+	for _, child := range board.PseudoLegalMoves(capturesOnly) {
+		move := child.LastMove
+		if board.CapturedPiece(move) != nil {
+			if pieceLetter(board.Board[move.From.Y][move.From.X]) == 'K' {
+				continue
+			}
+			child.explode(move.To)
+		}
+		boards = append(boards, child)
+	}
+	return boards
+}
+
+// IsLegal requires the side that just moved to keep its king, and out of
+// check unless the move exploded the enemy king
+func (Atomic) IsLegal(board *ChessBoard) bool {
+	mover := !board.NextTurn
+	if _, found := board.FindKing(mover); !found {
+		return false
+	}
+	if _, found := board.FindKing(board.NextTurn); !found {
+		return true
+	}
+	return !board.isAtomicCheck(mover)
+}
+
+func (Atomic) InCheck(board *ChessBoard) bool {
+	return board.isAtomicCheck(board.NextTurn)
+}
+
+// Decided is a loss for a side whose king has exploded
+func (Atomic) Decided(board *ChessBoard) Result {
+	if _, found := board.FindKing(board.NextTurn); !found {
+		return winFor(!board.NextTurn)
+	}
+	return Ongoing
+}
+
+// Result adds exploded kings to checkmate and stalemate
+func (variant Atomic) Result(board *ChessBoard, moves []*ChessBoard) Result {
+	if result := variant.Decided(board); result != Ongoing {
+		return result
+	}
+	switch {
+	case len(moves) > 0:
+		return Ongoing
+	case board.isAtomicCheck(board.NextTurn):
+		return winFor(!board.NextTurn)
+	}
+	return Draw
+}
+
+// explode removes the piece that captured on center and every piece other
+// than a pawn around it, with the castling rights of any king or rook lost
+func (cb *ChessBoard) explode(center Coordinates) {
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			square := Coordinates{X: center.X + dx, Y: center.Y + dy}
+			if !cb.IsWithinBounds(square) {
+				continue
+			}
+			piece := cb.Board[square.Y][square.X]
+			if piece == nil || (square != center && pieceLetter(piece) == 'P') {
+				continue
+			}
+			cb.clearCastling(square, square)
+			cb.Board[square.Y][square.X] = nil
+			cb.Promoted[square.Y][square.X] = false
+		}
+	}
+}
+
+// isAtomicCheck reports whether the king of the given color is attacked
+// under atomic rules
+func (cb *ChessBoard) isAtomicCheck(color bool) bool {
+	king, found := cb.FindKing(color)
+	if !found {
+		return false
+	}
+	return cb.isAtomicAttack(king, color)
+}
+
+// isAtomicAttack reports whether a king of the given color standing on square
+// would be in check. It never is while touching the enemy king.
+func (cb *ChessBoard) isAtomicAttack(square Coordinates, color bool) bool {
+	enemy, found := cb.FindKing(!color)
+	if found && max(square.X-enemy.X, enemy.X-square.X) <= 1 && max(square.Y-enemy.Y, enemy.Y-square.Y) <= 1 {
+		return false
+	}
+	return cb.IsSquareAttacked(square, !color)
+}
+
+// isAtomicKingLost reports whether the king of the given color has exploded
+// or is in check under atomic rules. With its own king safe, a side whose
+// move exploded the enemy king has won and cannot be in check.
+func (cb *ChessBoard) isAtomicKingLost(color bool) bool {
+	if _, found := cb.FindKing(color); !found {
+		return true
+	}
+	if _, found := cb.FindKing(!color); !found {
+		return false
+	}
+	return cb.isAtomicCheck(color)
+}
+
+// withExplosion returns child, reached by a move on this board, with the
+// explosion applied if the board is atomic and the move a capture. Other
+// children are returned as they are.
+func (cb *ChessBoard) withExplosion(child *ChessBoard) *ChessBoard {
+	move := child.LastMove
+	if cb.Variant != atomicName || cb.CapturedPiece(move) == nil {
+		return child
+	}
+	exploded := child.DeepCopy()
+	exploded.explode(move.To)
+	return exploded
+}
+
+// isKingSquareAttacked reports whether a king of the given color would be in
+// check on square, following atomic rules on atomic boards
+func (cb *ChessBoard) isKingSquareAttacked(square Coordinates, color bool) bool {
+	if cb.Variant == atomicName {
+		return cb.isAtomicAttack(square, color)
+	}
+	return cb.IsSquareAttacked(square, !color)
+}
@@ -0,0 +1,74 @@
+package components
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAtomicAdjacentKings(t *testing.T) {
+	// The rook on e8 attacks the white king, which touches the black king
+	for _, test := range []struct {
+		variant string
+		check   bool
+	}{
+		{standardName, true},
+		{atomicName, false},
+	} {
+		board := parseVariantFEN(t, "4r3/8/8/3k4/4K3/8/8/8 w - - 0 1", test.variant)
+		if got := board.WouldLeaveKingInCheck(true, nil, 0); got != test.check {
+			t.Errorf("%s: WouldLeaveKingInCheck %v, want %v", test.variant, got, test.check)
+		}
+		if got := VariantOf(board).InCheck(board); got != test.check {
+			t.Errorf("%s: InCheck %v, want %v", test.variant, got, test.check)
+		}
+	}
+
+	// Only in atomic may the king step next to the enemy king
+	for _, test := range []struct {
+		variant string
+		want    []string
+	}{
+		{standardName, []string{"e1d1", "e1f1"}},
+		{atomicName, []string{"e1d1", "e1d2", "e1e2", "e1f1", "e1f2"}},
+	} {
+		board := parseVariantFEN(t, "8/8/8/8/8/4k3/8/4K3 w - - 0 1", test.variant)
+		position := Coordinates{X: 4, Y: 0}
+		got := moveNames(board.Board[0][4].GetPossibleMoves(*board, position, false))
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: king moves %v, want %v", test.variant, got, test.want)
+		}
+	}
+}
+
+func TestAtomicOwnKingExplosion(t *testing.T) {
+	// Taking the knight on d3 explodes the white king on d2
+	const fen = "7k/8/8/8/8/3n4/3KP3/8 w - - 0 1"
+	for _, test := range []struct {
+		variant string
+		allowed bool
+	}{
+		{standardName, true},
+		{atomicName, false},
+	} {
+		board := parseVariantFEN(t, fen, test.variant)
+		pawn := Coordinates{X: 4, Y: 1}
+		if got := hasMove(board.Board[1][4].GetPossibleMoves(*board, pawn, true), "e2d3"); got != test.allowed {
+			t.Errorf("%s: pawn capture generated %v, want %v", test.variant, got, test.allowed)
+		}
+		king := Coordinates{X: 3, Y: 1}
+		if got := hasMove(board.Board[1][3].GetPossibleMoves(*board, king, false), "d2d3"); got != test.allowed {
+			t.Errorf("%s: king capture generated %v, want %v", test.variant, got, test.allowed)
+		}
+		if got := hasMove(LegalMoves(VariantOf(board), board), "e2d3"); got != test.allowed {
+			t.Errorf("%s: pawn capture legal %v, want %v", test.variant, got, test.allowed)
+		}
+	}
+
+	// An exploded king is as lost as one left in check
+	board := parseVariantFEN(t, fen, atomicName)
+	for _, child := range (Atomic{}).PseudoLegalMoves(board, true) {
+		if child.LastMove.UCI() == "e2d3" && !child.WouldLeaveKingInCheck(true, nil, 0) {
+			t.Error("exploding the own king does not leave it in check")
+		}
+	}
+}
@@ -3,6 +3,7 @@ package components
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type Coordinates struct {
@@ -10,10 +11,48 @@ type Coordinates struct {
 	Y int
 }
 
+// Ranks is the number of ranks of every board. MaxFiles is the widest board
+// supported; narrower boards leave the files past their width empty.
+const (
+	Ranks    = 8
+	MaxFiles = 10
+)
+
 type ChessBoard struct {
-	Board    [8][8]ChessPiece
-	Score    int  // + white / - black
-	NextTurn bool // true = white, false = black
+	Board     [Ranks][MaxFiles]ChessPiece
+	Score     int  // + white / - black
+	NextTurn  bool // true = white, false = black
+	Castling  CastlingRights
+	EnPassant *Coordinates // square a pawn can capture en passant, nil if none
+	LastMove  Move         // move that produced this board, not part of the position
+
+	// Chess960 boards castle with the rooks on CastlingFiles
+	Chess960      bool
+	CastlingFiles CastlingFiles
+
+	// Variant names the rule set the board is played under, see VariantOf.
+	// It is empty for standard chess.
+	Variant string
+
+	// Files is the width of boards wider than the standard eight files,
+	// such as Capablanca chess; it is zero on standard width boards
+	Files int
+
+	// Crazyhouse pockets, White's first, and the squares holding promoted
+	// pieces, which go back to the pocket as pawns when captured
+	Pockets  [2]Pocket
+	Promoted [Ranks][MaxFiles]bool
+
+	// Three-check counts of the checks given by White and by Black
+	Checks [2]int
+}
+
+// CastlingRights records which castling moves are still available
+type CastlingRights struct {
+	WhiteKingside  bool
+	WhiteQueenside bool
+	BlackKingside  bool
+	BlackQueenside bool
 }
 
 type ChessPieceJSON struct {
@@ -21,10 +60,21 @@ type ChessPieceJSON struct {
 	Color bool
 }
 
+// Width returns the number of files of the board
+func (cb *ChessBoard) Width() int {
+	if cb.Files == 0 {
+		return 8
+	}
+	return cb.Files
+}
+
 func (cb *ChessBoard) MarshalJSON() ([]byte, error) {
-	board := [8][8]ChessPieceJSON{}
+	// Rows are cut to the width of the board, so the stored state of a
+	// standard board is unchanged and a wide board's width is kept
+	board := make([][]ChessPieceJSON, Ranks)
 	for y, row := range cb.Board {
-		for x, piece := range row {
+		board[y] = make([]ChessPieceJSON, cb.Width())
+		for x, piece := range row[:cb.Width()] {
 			if piece != nil {
 				board[y][x] = ChessPieceJSON{
 					Type:  piece.ToString(),
@@ -33,42 +83,107 @@ func (cb *ChessBoard) MarshalJSON() ([]byte, error) {
 			}
 		}
 	}
+	// Chess960 fields are left out of standard boards so their stored
+	// states stay unchanged
+	var files *CastlingFiles
+	if cb.Chess960 {
+		files = &cb.CastlingFiles
+	}
+	// Likewise pockets are only stored for crazyhouse boards, where they are
+	// part of the position
+	var pockets *[2]Pocket
+	if cb.Variant == crazyhouseName {
+		pockets = &cb.Pockets
+	}
+	var checks *[2]int
+	if cb.Variant == threeCheckName {
+		checks = &cb.Checks
+	}
+	var promoted []Coordinates
+	for y, row := range cb.Promoted {
+		for x, isPromoted := range row {
+			if isPromoted {
+				promoted = append(promoted, Coordinates{X: x, Y: y})
+			}
+		}
+	}
 	return json.Marshal(struct {
-		Board    [8][8]ChessPieceJSON
-		Score    int
-		NextTurn bool
+		Board         [][]ChessPieceJSON
+		Score         int
+		NextTurn      bool
+		Castling      CastlingRights
+		EnPassant     *Coordinates   `json:",omitempty"`
+		Chess960      bool           `json:",omitempty"`
+		CastlingFiles *CastlingFiles `json:",omitempty"`
+		Variant       string         `json:",omitempty"`
+		Pockets       *[2]Pocket     `json:",omitempty"`
+		Promoted      []Coordinates  `json:",omitempty"`
+		Checks        *[2]int        `json:",omitempty"`
 	}{
-		Board:    board,
-		Score:    cb.Score,
-		NextTurn: cb.NextTurn,
+		Board:         board,
+		Score:         cb.Score,
+		NextTurn:      cb.NextTurn,
+		Castling:      cb.Castling,
+		EnPassant:     cb.EnPassant,
+		Chess960:      cb.Chess960,
+		CastlingFiles: files,
+		Variant:       cb.Variant,
+		Pockets:       pockets,
+		Promoted:      promoted,
+		Checks:        checks,
 	})
 }
 
 func (cb *ChessBoard) UnmarshalJSON(data []byte) error {
 	aux := struct {
-		Board    [8][8]ChessPieceJSON
-		Score    int
-		NextTurn bool
+		Board         [][]ChessPieceJSON
+		Score         int
+		NextTurn      bool
+		Castling      CastlingRights
+		EnPassant     *Coordinates
+		Chess960      bool
+		CastlingFiles *CastlingFiles
+		Variant       string
+		Pockets       *[2]Pocket
+		Promoted      []Coordinates
+		Checks        *[2]int
 	}{}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
 
+	if len(aux.Board) != Ranks {
+		return fmt.Errorf("board has %d ranks, expected %d", len(aux.Board), Ranks)
+	}
+	*cb = ChessBoard{}
+	if width := len(aux.Board[0]); width != 8 {
+		if width < 1 || width > MaxFiles {
+			return fmt.Errorf("board has %d files, expected at most %d", width, MaxFiles)
+		}
+		cb.Files = width
+	}
 	for y, row := range aux.Board {
+		if len(row) != cb.Width() {
+			return fmt.Errorf("rank %d has %d files, expected %d", y+1, len(row), cb.Width())
+		}
 		for x, pieceJSON := range row {
 			switch pieceJSON.Type {
 			case " W P ", " B P ":
-				cb.Board[y][x] = &Pawn{Color: pieceJSON.Color}
+				cb.Board[y][x] = Pawn{Color: pieceJSON.Color}
 			case " W R ", " B R ":
-				cb.Board[y][x] = &Rook{Color: pieceJSON.Color}
+				cb.Board[y][x] = Rook{Color: pieceJSON.Color}
 			case " W N ", " B N ":
-				cb.Board[y][x] = &Knight{Color: pieceJSON.Color}
+				cb.Board[y][x] = Knight{Color: pieceJSON.Color}
 			case " W B ", " B B ":
-				cb.Board[y][x] = &Bishop{Color: pieceJSON.Color}
+				cb.Board[y][x] = Bishop{Color: pieceJSON.Color}
 			case " W Q ", " B Q ":
-				cb.Board[y][x] = &Queen{Color: pieceJSON.Color}
+				cb.Board[y][x] = Queen{Color: pieceJSON.Color}
 			case " W K ", " B K ":
-				cb.Board[y][x] = &King{Color: pieceJSON.Color}
+				cb.Board[y][x] = King{Color: pieceJSON.Color}
+			case " W A ", " B A ":
+				cb.Board[y][x] = Archbishop{Color: pieceJSON.Color}
+			case " W C ", " B C ":
+				cb.Board[y][x] = Chancellor{Color: pieceJSON.Color}
 			default:
 				cb.Board[y][x] = nil
 			}
@@ -77,6 +192,24 @@ func (cb *ChessBoard) UnmarshalJSON(data []byte) error {
 
 	cb.Score = aux.Score
 	cb.NextTurn = aux.NextTurn
+	cb.Castling = aux.Castling
+	cb.EnPassant = aux.EnPassant
+	cb.Chess960 = aux.Chess960
+	cb.Variant = aux.Variant
+	if aux.CastlingFiles != nil {
+		cb.CastlingFiles = *aux.CastlingFiles
+	}
+	if aux.Pockets != nil {
+		cb.Pockets = *aux.Pockets
+	}
+	if aux.Checks != nil {
+		cb.Checks = *aux.Checks
+	}
+	for _, square := range aux.Promoted {
+		if cb.IsWithinBounds(square) {
+			cb.Promoted[square.Y][square.X] = true
+		}
+	}
 	return nil
 }
 
@@ -87,25 +220,44 @@ type AttackCache struct {
 
 // Temp for Debug -> remake for more efficient storage later
 func (chessBoard ChessBoard) ToString() string {
-	fmt.Println("  ------------------------------------------")
+	fmt.Println(boardBorder(chessBoard.Width()))
 	for rNum, row := range chessBoard.Board {
 		fmt.Print(rNum, "-|")
-		for _, piece := range row {
+		for _, piece := range row[:chessBoard.Width()] {
 			if piece != nil {
 				fmt.Print(piece.ToString())
 			} else {
 				fmt.Print("     ") // Print empty space for nil pieces
 			}
 		}
-		fmt.Println("|\n")
+		fmt.Print("|\n\n")
+	}
+	fmt.Println(boardBorder(chessBoard.Width()))
+	fmt.Println(fileLabels(chessBoard.Width()))
+	if chessBoard.hasPockets() {
+		fmt.Printf("  Pockets: White [%s] Black [%s]\n", chessBoard.Pocket(true), chessBoard.Pocket(false))
 	}
-	fmt.Println("  ------------------------------------------")
-	fmt.Println("     A    B    C    D    E    F    G    H  ")
 	return ""
 }
 
+// boardBorder returns the line drawn above and below a board of the given
+// width by ToString
+func boardBorder(width int) string {
+	return "  " + strings.Repeat("-", 5*width+2)
+}
+
+// fileLabels returns the file letters printed under a board of the given
+// width by ToString
+func fileLabels(width int) string {
+	labels := "  "
+	for x := 0; x < width; x++ {
+		labels += fmt.Sprintf("   %c ", 'A'+x)
+	}
+	return labels + " "
+}
+
 func (cb *ChessBoard) IsEmpty(position Coordinates) bool {
-	if position.X < 0 || position.X >= 8 || position.Y < 0 || position.Y >= 8 {
+	if !cb.IsWithinBounds(position) {
 		// fmt.Println("out of bounds")
 		return false
 	}
@@ -115,12 +267,24 @@ func (cb *ChessBoard) IsEmpty(position Coordinates) bool {
 
 func (cb *ChessBoard) DeepCopy() *ChessBoard {
 	newBoard := ChessBoard{
-		Score:    cb.Score,
-		NextTurn: cb.NextTurn,
+		Score:     cb.Score,
+		NextTurn:  cb.NextTurn,
+		Castling:  cb.Castling,
+		EnPassant: cb.EnPassant,
+		LastMove:  cb.LastMove,
+
+		Chess960:      cb.Chess960,
+		CastlingFiles: cb.CastlingFiles,
+		Variant:       cb.Variant,
+		Files:         cb.Files,
+
+		Pockets:  cb.Pockets,
+		Promoted: cb.Promoted,
+		Checks:   cb.Checks,
 	}
 
-	for i := 0; i < 8; i++ {
-		for j := 0; j < 8; j++ {
+	for i := 0; i < Ranks; i++ {
+		for j := 0; j < MaxFiles; j++ {
 			newBoard.Board[i][j] = cb.Board[i][j]
 		}
 	}
@@ -129,7 +293,7 @@ func (cb *ChessBoard) DeepCopy() *ChessBoard {
 }
 
 func (cb *ChessBoard) MovePiece(from, to Coordinates) {
-	if from.X < 0 || from.X >= 8 || from.Y < 0 || from.Y >= 8 || cb.Board[from.Y][from.X] == nil {
+	if !cb.IsWithinBounds(from) || cb.Board[from.Y][from.X] == nil {
 		return
 	}
 	// fmt.Println("FROM: ", from)
@@ -137,10 +301,17 @@ func (cb *ChessBoard) MovePiece(from, to Coordinates) {
 
 	cb.Board[to.Y][to.X] = cb.Board[from.Y][from.X]
 	cb.Board[from.Y][from.X] = nil
+	cb.Promoted[to.Y][to.X] = cb.Promoted[from.Y][from.X]
+	cb.Promoted[from.Y][from.X] = false
+	cb.LastMove = Move{From: from, To: to}
+
+	// Any move ends the en passant chance; a double pawn step sets it again
+	cb.EnPassant = nil
+	cb.clearCastling(from, to)
 }
 
 func (cb *ChessBoard) IsEnemy(position Coordinates, color bool) bool {
-	if position.X < 0 || position.X >= 8 || position.Y < 0 || position.Y >= 8 {
+	if !cb.IsWithinBounds(position) {
 		return false
 	}
 
@@ -148,21 +319,24 @@ func (cb *ChessBoard) IsEnemy(position Coordinates, color bool) bool {
 	return piece != nil && piece.GetColor() != color
 }
 
-// Compute attacks directly without recursion
+// Compute attacks directly without recursion. On atomic boards kings attack
+// nothing, as they cannot capture.
 func (cb *ChessBoard) ComputeAttacks(color bool) map[Coordinates]bool {
 	attacks := make(map[Coordinates]bool)
 
 	// For each piece on the board
-	for y := 0; y < 8; y++ {
-		for x := 0; x < 8; x++ {
+	for y := 0; y < Ranks; y++ {
+		for x := 0; x < cb.Width(); x++ {
 			piece := cb.Board[y][x]
 			if piece == nil || piece.GetColor() != color {
 				continue
 			}
 
-			// Handle different piece types directly
-			switch piece.ToString() {
-			case " W P ", " B P ":
+			// Handle different piece types directly. The compound pieces
+			// combine the knight's jumps with a bishop's or rook's lines.
+			letter := pieceLetter(piece)
+			switch letter {
+			case 'P':
 				// Pawns attack diagonally
 				direction := 1
 				if !piece.GetColor() {
@@ -171,25 +345,14 @@ func (cb *ChessBoard) ComputeAttacks(color bool) map[Coordinates]bool {
 				if x > 0 {
 					attacks[Coordinates{X: x - 1, Y: y + direction}] = true
 				}
-				if x < 7 {
+				if x < cb.Width()-1 {
 					attacks[Coordinates{X: x + 1, Y: y + direction}] = true
 				}
 
-			case " W N ", " B N ":
-				// Knight moves
-				knightMoves := []Coordinates{
-					{X: x + 2, Y: y + 1}, {X: x + 2, Y: y - 1},
-					{X: x - 2, Y: y + 1}, {X: x - 2, Y: y - 1},
-					{X: x + 1, Y: y + 2}, {X: x + 1, Y: y - 2},
-					{X: x - 1, Y: y + 2}, {X: x - 1, Y: y - 2},
-				}
-				for _, move := range knightMoves {
-					if cb.IsWithinBounds(move) {
-						attacks[move] = true
-					}
+			case 'K':
+				if cb.Variant == atomicName {
+					break
 				}
-
-			case " W K ", " B K ":
 				// King moves (one square in any direction)
 				for dx := -1; dx <= 1; dx++ {
 					for dy := -1; dy <= 1; dy++ {
@@ -202,40 +365,41 @@ func (cb *ChessBoard) ComputeAttacks(color bool) map[Coordinates]bool {
 						}
 					}
 				}
+			}
 
-			case " W B ", " B B ", " W Q ", " B Q ":
-				// Bishop/Queen diagonal moves
-				for i := 1; i < 8; i++ {
-					directions := []Coordinates{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
-					for _, dir := range directions {
-						newPos := Coordinates{X: x + i*dir.X, Y: y + i*dir.Y}
-						if !cb.IsWithinBounds(newPos) {
-							break
-						}
-						attacks[newPos] = true
-						if !cb.IsEmpty(newPos) {
-							break
-						}
-					}
+			if letter == 'N' || letter == 'A' || letter == 'C' {
+				// Knight moves
+				knightMoves := []Coordinates{
+					{X: x + 2, Y: y + 1}, {X: x + 2, Y: y - 1},
+					{X: x - 2, Y: y + 1}, {X: x - 2, Y: y - 1},
+					{X: x + 1, Y: y + 2}, {X: x + 1, Y: y - 2},
+					{X: x - 1, Y: y + 2}, {X: x - 1, Y: y - 2},
 				}
-				if piece.ToString() == " W B " || piece.ToString() == " B B " {
-					break
+				for _, move := range knightMoves {
+					if cb.IsWithinBounds(move) {
+						attacks[move] = true
+					}
 				}
-				fallthrough // Continue to rook moves for queen
+			}
 
-			case " W R ", " B R ":
-				// Rook/Queen straight moves
-				for i := 1; i < 8; i++ {
-					directions := []Coordinates{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
-					for _, dir := range directions {
-						newPos := Coordinates{X: x + i*dir.X, Y: y + i*dir.Y}
-						if !cb.IsWithinBounds(newPos) {
-							break
-						}
-						attacks[newPos] = true
-						if !cb.IsEmpty(newPos) {
-							break
-						}
+			var directions []Coordinates
+			if letter == 'B' || letter == 'Q' || letter == 'A' {
+				// Diagonal moves
+				directions = append(directions, Coordinates{1, 1}, Coordinates{1, -1}, Coordinates{-1, 1}, Coordinates{-1, -1})
+			}
+			if letter == 'R' || letter == 'Q' || letter == 'C' {
+				// Straight moves
+				directions = append(directions, Coordinates{1, 0}, Coordinates{-1, 0}, Coordinates{0, 1}, Coordinates{0, -1})
+			}
+			for _, dir := range directions {
+				for i := 1; ; i++ {
+					newPos := Coordinates{X: x + i*dir.X, Y: y + i*dir.Y}
+					if !cb.IsWithinBounds(newPos) {
+						break
+					}
+					attacks[newPos] = true
+					if !cb.IsEmpty(newPos) {
+						break
 					}
 				}
 			}
@@ -244,18 +408,17 @@ func (cb *ChessBoard) ComputeAttacks(color bool) map[Coordinates]bool {
 	return attacks
 }
 
+// WouldLeaveKingInCheck reports whether the king of the given color is
+// attacked, following atomic rules on atomic boards
 func (cb *ChessBoard) WouldLeaveKingInCheck(color bool, cache *AttackCache, _ int) bool {
+	if cb.Variant == atomicName {
+		return cb.isAtomicKingLost(color)
+	}
+
 	// Find king position
-	var kingPosition Coordinates
-	found := false
-	for x := 0; x < 8 && !found; x++ {
-		for y := 0; y < 8 && !found; y++ {
-			piece := cb.Board[y][x]
-			if piece != nil && piece.GetColor() == color && piece.ToString() == " K " {
-				kingPosition = Coordinates{X: x, Y: y}
-				found = true
-			}
-		}
+	kingPosition, found := cb.FindKing(color)
+	if !found {
+		return false
 	}
 
 	// Use cached attacks if available
@@ -276,5 +439,5 @@ func (cb *ChessBoard) WouldLeaveKingInCheck(color bool, cache *AttackCache, _ in
 }
 
 func (cb *ChessBoard) IsWithinBounds(position Coordinates) bool {
-	return position.X >= 0 && position.X < 8 && position.Y >= 0 && position.Y < 8
+	return position.X >= 0 && position.X < cb.Width() && position.Y >= 0 && position.Y < Ranks
 }
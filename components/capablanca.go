@@ -0,0 +1,43 @@
+package components
+
+const capablancaName = "capablanca"
+
+// capablancaFiles is the width of a Capablanca board
+const capablancaFiles = 10
+
+// CapablancaFEN is the Capablanca chess start position
+const CapablancaFEN = "rnabqkbcnr/pppppppppp/10/10/10/10/PPPPPPPPPP/RNABQKBCNR w KQkq - 0 1"
+
+// Capablanca is chess on a ten file board with two compound pieces, the
+// archbishop between the queenside knight and bishop and the chancellor
+// between the kingside bishop and knight. Pawns may also promote to either,
+// and the king castles three files towards the rook.
+type Capablanca struct {
+	Standard
+}
+
+func (Capablanca) Name() string {
+	return capablancaName
+}
+
+func (Capablanca) StartPosition() ChessBoard {
+	board, _ := ParseFEN(CapablancaFEN)
+	return board
+}
+
+// PseudoLegalMoves adds promotions to the compound pieces
+func (Capablanca) PseudoLegalMoves(board *ChessBoard, capturesOnly bool) []*ChessBoard {
+	children := board.PseudoLegalMoves(capturesOnly)
+	for _, child := range children {
+		if child.LastMove.Promotion != 'Q' {
+			continue
+		}
+		for _, letter := range []byte{'A', 'C'} {
+			promoted := child.DeepCopy()
+			promoted.Board[promoted.LastMove.To.Y][promoted.LastMove.To.X] = NewPiece(letter, board.NextTurn)
+			promoted.LastMove.Promotion = letter
+			children = append(children, promoted)
+		}
+	}
+	return children
+}
@@ -0,0 +1,95 @@
+package components
+
+import "testing"
+
+func TestCapablancaPerft(t *testing.T) {
+	board := Capablanca{}.StartPosition()
+	if board.Width() != capablancaFiles {
+		t.Fatalf("start position is %d files wide, want %d", board.Width(), capablancaFiles)
+	}
+	if fen := board.ToFEN(); fen != CapablancaFEN {
+		t.Errorf("start position FEN %s, want %s", fen, CapablancaFEN)
+	}
+	for depth, want := range []int{1, 28, 784, 25228} {
+		if got := perft(Capablanca{}, &board, depth); got != want {
+			t.Errorf("perft(%d) = %d, want %d", depth, got, want)
+		}
+	}
+}
+
+func TestCapablancaCompoundPieces(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		fen     string
+		moves   int
+		samples []string
+	}{
+		// 14 bishop moves and 8 knight jumps
+		{"archbishop", "9k/10/10/10/4A5/10/10/9K w - - 0 1", 22, []string{"e4i8", "e4h1", "e4f6", "e4c3"}},
+		// 16 rook moves and 8 knight jumps
+		{"chancellor", "9k/10/10/10/4C5/10/10/9K w - - 0 1", 24, []string{"e4a4", "e4i4", "e4e8", "e4d6"}},
+	} {
+		board, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if board.Variant != capablancaName {
+			t.Errorf("%s: variant %q, want %q", test.name, board.Variant, capablancaName)
+		}
+		moves := board.Board[3][4].GetPossibleMoves(board, Coordinates{X: 4, Y: 3}, true)
+		if len(moves) != test.moves {
+			t.Errorf("%s: %d moves %v, want %d", test.name, len(moves), moveNames(moves), test.moves)
+		}
+		for _, sample := range test.samples {
+			if !hasMove(moves, sample) {
+				t.Errorf("%s: %s missing from %v", test.name, sample, moveNames(moves))
+			}
+		}
+	}
+
+	board, err := ParseFEN("k9/4P5/10/10/10/10/10/K9 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moves := LegalMoves(Capablanca{}, &board)
+	for _, promotion := range []string{"e7e8a", "e7e8c", "e7e8q"} {
+		if !hasMove(moves, promotion) {
+			t.Errorf("promotion %s missing from %v", promotion, moveNames(moves))
+		}
+	}
+}
+
+func TestCapablancaCastling(t *testing.T) {
+	board, err := ParseFEN("r4k3r/10/10/10/10/10/10/R4K3R w KQkq - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		move       string
+		king, rook int
+		rookFrom   int
+	}{
+		{"f1i1", 8, 7, 9},
+		{"f1c1", 2, 3, 0},
+	} {
+		child := playMove(t, Capablanca{}, &board, test.move)
+		if !child.isPieceAt(Coordinates{X: test.king, Y: 0}, true, 'K') || !child.isPieceAt(Coordinates{X: test.rook, Y: 0}, true, 'R') {
+			t.Errorf("%s: king and rook not on files %d and %d: %s", test.move, test.king, test.rook, child.ToFEN())
+		}
+		if !child.IsEmpty(Coordinates{X: test.rookFrom, Y: 0}) || !child.IsEmpty(Coordinates{X: 5, Y: 0}) {
+			t.Errorf("%s: king or rook left behind: %s", test.move, child.ToFEN())
+		}
+		if child.Castling.WhiteKingside || child.Castling.WhiteQueenside {
+			t.Errorf("%s: white keeps castling rights", test.move)
+		}
+	}
+
+	// The king may not cross an attacked square, here h1
+	board, err = ParseFEN("r4k1r2/10/10/10/10/10/10/R4K3R w KQ - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moves := LegalMoves(Capablanca{}, &board); hasMove(moves, "f1i1") || !hasMove(moves, "f1c1") {
+		t.Errorf("castling through check: moves %v", moveNames(moves))
+	}
+}
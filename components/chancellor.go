@@ -0,0 +1,43 @@
+package components
+
+// Chancellor moves as a rook and as a knight. It is one of the two compound pieces of
+// Capablanca chess.
+type Chancellor struct {
+	Color bool // white = true, black = false
+}
+
+func (chancellor Chancellor) GetPossibleMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard {
+	possibleBoards := Rook{Color: chancellor.Color}.GetPossibleMoves(board, position, false)
+	possibleBoards = append(possibleBoards, Knight{Color: chancellor.Color}.GetPossibleMoves(board, position, false)...)
+
+	// Filter boards if inCheck is true
+	if inCheck {
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, chancellor.Color)
+	}
+
+	return possibleBoards
+}
+
+// GetCaptureMoves returns only the moves that take an enemy piece
+func (chancellor Chancellor) GetCaptureMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard {
+	possibleBoards := Rook{Color: chancellor.Color}.GetCaptureMoves(board, position, false)
+	possibleBoards = append(possibleBoards, Knight{Color: chancellor.Color}.GetCaptureMoves(board, position, false)...)
+
+	if inCheck {
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, chancellor.Color)
+	}
+
+	return possibleBoards
+}
+
+func (chancellor Chancellor) GetColor() bool {
+	return chancellor.Color
+}
+
+func (chancellor Chancellor) ToString() string {
+	if chancellor.Color {
+		return " W C "
+	} else {
+		return " B C "
+	}
+}
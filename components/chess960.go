@@ -0,0 +1,318 @@
+package components
+
+import "fmt"
+
+// CastlingFiles are the files the castling rooks start on. They are only
+// used in Chess960; standard chess always castles with the rooks in the
+// corners, see standardCastlingFiles.
+type CastlingFiles struct {
+	WhiteKingside  int
+	WhiteQueenside int
+	BlackKingside  int
+	BlackQueenside int
+}
+
+// standardCastlingFiles returns the corner files the rooks castle from
+// outside Chess960
+func (cb *ChessBoard) standardCastlingFiles() CastlingFiles {
+	last := cb.Width() - 1
+	return CastlingFiles{
+		WhiteKingside: last, WhiteQueenside: 0,
+		BlackKingside: last, BlackQueenside: 0,
+	}
+}
+
+// standardKingFile returns the file the king starts on outside Chess960, e
+// on a standard board and f on a Capablanca board
+func (cb *ChessBoard) standardKingFile() int {
+	return cb.Width() / 2
+}
+
+// Chess960Positions is the number of Chess960 start positions
+const Chess960Positions = 960
+
+// chess960KnightPlacements lists the squares taken by the two knights among
+// the five squares left after placing the bishops and the queen, in the
+// order of the standard numbering
+var chess960KnightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960Position returns the Chess960 start position with the given index
+// in the standard numbering, where 518 is the standard start position
+func Chess960Position(index int) (ChessBoard, error) {
+	var board ChessBoard
+	if index < 0 || index >= Chess960Positions {
+		return board, fmt.Errorf("invalid Chess960 position %d: expected 0 to %d", index, Chess960Positions-1)
+	}
+
+	var rank [8]byte
+	n := index
+	rank[2*(n%4)+1] = 'B'
+	n /= 4
+	rank[2*(n%4)] = 'B'
+	n /= 4
+
+	// The remaining pieces fill the empty squares from the a-file on
+	place := func(letter byte, nth int) {
+		for x := range rank {
+			if rank[x] != 0 {
+				continue
+			}
+			if nth == 0 {
+				rank[x] = letter
+				return
+			}
+			nth--
+		}
+	}
+	place('Q', n%6)
+	n /= 6
+	knights := chess960KnightPlacements[n]
+	place('N', knights[1])
+	place('N', knights[0])
+	place('R', 0)
+	place('K', 0)
+	place('R', 0)
+
+	for x, letter := range rank {
+		board.Board[0][x] = NewPiece(letter, true)
+		board.Board[1][x] = NewPiece('P', true)
+		board.Board[6][x] = NewPiece('P', false)
+		board.Board[7][x] = NewPiece(letter, false)
+	}
+	board.NextTurn = true
+	board.Chess960 = true
+	board.Castling = CastlingRights{
+		WhiteKingside: true, WhiteQueenside: true,
+		BlackKingside: true, BlackQueenside: true,
+	}
+	board.CastlingFiles = board.outermostRookFiles()
+	return board, nil
+}
+
+// castlingFiles returns the rook files castling uses on this board
+func (cb *ChessBoard) castlingFiles() CastlingFiles {
+	if cb.Chess960 {
+		return cb.CastlingFiles
+	}
+	return cb.standardCastlingFiles()
+}
+
+// castlingRookFile returns the starting file of the rook castling on the
+// given side
+func (cb *ChessBoard) castlingRookFile(color, kingside bool) int {
+	return cb.castlingFiles().rookFile(color, kingside)
+}
+
+func (files CastlingFiles) rookFile(color, kingside bool) int {
+	switch {
+	case color && kingside:
+		return files.WhiteKingside
+	case color:
+		return files.WhiteQueenside
+	case kingside:
+		return files.BlackKingside
+	default:
+		return files.BlackQueenside
+	}
+}
+
+func (files *CastlingFiles) setRookFile(color, kingside bool, file int) {
+	switch {
+	case color && kingside:
+		files.WhiteKingside = file
+	case color:
+		files.WhiteQueenside = file
+	case kingside:
+		files.BlackKingside = file
+	default:
+		files.BlackQueenside = file
+	}
+}
+
+// outermostRookFiles finds the rooks furthest from the king on each side of
+// each back rank, which is where the rooks of a start position stand. Sides
+// without such a rook keep the standard file.
+func (cb *ChessBoard) outermostRookFiles() CastlingFiles {
+	files := cb.standardCastlingFiles()
+	for _, color := range []bool{true, false} {
+		rank := 0
+		if !color {
+			rank = 7
+		}
+		king, found := cb.FindKing(color)
+		if !found || king.Y != rank {
+			continue
+		}
+		kingside, queenside := -1, -1
+		for x := 0; x < cb.Width(); x++ {
+			if !cb.isPieceAt(Coordinates{X: x, Y: rank}, color, 'R') {
+				continue
+			}
+			if x > king.X {
+				kingside = x
+			} else if queenside < 0 {
+				queenside = x
+			}
+		}
+		if kingside >= 0 {
+			files.setRookFile(color, true, kingside)
+		}
+		if queenside >= 0 {
+			files.setRookFile(color, false, queenside)
+		}
+	}
+	return files
+}
+
+// clearCastling drops the castling rights that a move from one square to
+// another ends: any king move, and any move from or onto a castling rook's
+// starting square
+func (cb *ChessBoard) clearCastling(from, to Coordinates) {
+	if mover := cb.Board[to.Y][to.X]; mover != nil && pieceLetter(mover) == 'K' {
+		cb.clearCastlingFor(mover.GetColor())
+	}
+	files := cb.castlingFiles()
+	for _, square := range []Coordinates{from, to} {
+		switch square {
+		case Coordinates{X: files.WhiteKingside, Y: 0}:
+			cb.Castling.WhiteKingside = false
+		case Coordinates{X: files.WhiteQueenside, Y: 0}:
+			cb.Castling.WhiteQueenside = false
+		case Coordinates{X: files.BlackKingside, Y: 7}:
+			cb.Castling.BlackKingside = false
+		case Coordinates{X: files.BlackQueenside, Y: 7}:
+			cb.Castling.BlackQueenside = false
+		}
+	}
+}
+
+func (cb *ChessBoard) clearCastlingFor(color bool) {
+	cb.Castling.set(color, true, false)
+	cb.Castling.set(color, false, false)
+}
+
+// IsCastling reports whether a move on this board castles. In Chess960 a
+// castling move is written as the king taking its own rook, otherwise as the
+// king moving more than one file.
+func (cb *ChessBoard) IsCastling(move Move) bool {
+	if move.Drop != 0 {
+		return false
+	}
+	mover := cb.Board[move.From.Y][move.From.X]
+	if mover == nil || pieceLetter(mover) != 'K' {
+		return false
+	}
+	if cb.Chess960 {
+		return cb.isPieceAt(move.To, mover.GetColor(), 'R')
+	}
+	return move.From.Y == move.To.Y && max(move.To.X-move.From.X, move.From.X-move.To.X) >= 2
+}
+
+// parseCastling reads the castling field of a FEN. K and Q stand for the
+// outermost rook on that side of the king as in X-FEN, and a file letter for
+// the rook on that file as in Shredder-FEN. A board whose castling does not
+// start from the standard squares becomes a Chess960 board.
+func (cb *ChessBoard) parseCastling(field string) error {
+	cb.Castling = CastlingRights{}
+	cb.CastlingFiles = cb.outermostRookFiles()
+	if field == "-" {
+		return nil
+	}
+
+	for _, char := range field {
+		color := char >= 'A' && char <= 'Z'
+		letter := byte(char)
+		if !color {
+			letter -= 'a' - 'A'
+		}
+		rank := 0
+		if !color {
+			rank = 7
+		}
+		king, found := cb.FindKing(color)
+		if !found || king.Y != rank {
+			return fmt.Errorf("castling rights %q without a king on its back rank", field)
+		}
+
+		var kingside bool
+		switch {
+		case letter == 'K' || letter == 'Q':
+			kingside = letter == 'K'
+		case letter >= 'A' && int(letter-'A') < cb.Width():
+			file := int(letter - 'A')
+			if file == king.X {
+				return fmt.Errorf("bad castling rights %q", field)
+			}
+			kingside = file > king.X
+			cb.CastlingFiles.setRookFile(color, kingside, file)
+			cb.Chess960 = true
+		default:
+			return fmt.Errorf("bad castling rights %q", field)
+		}
+		cb.Castling.set(color, kingside, true)
+
+		if king.X != cb.standardKingFile() || cb.CastlingFiles.rookFile(color, kingside) != cb.standardCastlingFiles().rookFile(color, kingside) {
+			cb.Chess960 = true
+		}
+	}
+	return nil
+}
+
+func (rights *CastlingRights) set(color, kingside, allowed bool) {
+	switch {
+	case color && kingside:
+		rights.WhiteKingside = allowed
+	case color:
+		rights.WhiteQueenside = allowed
+	case kingside:
+		rights.BlackKingside = allowed
+	default:
+		rights.BlackQueenside = allowed
+	}
+}
+
+func (rights CastlingRights) allowed(color, kingside bool) bool {
+	switch {
+	case color && kingside:
+		return rights.WhiteKingside
+	case color:
+		return rights.WhiteQueenside
+	case kingside:
+		return rights.BlackKingside
+	default:
+		return rights.BlackQueenside
+	}
+}
+
+// castlingField renders the castling rights of a FEN. Standard boards use
+// KQkq. Chess960 boards use X-FEN, which names a rook's file only when it is
+// not the outermost rook on its side, or Shredder-FEN, which always does.
+func (cb *ChessBoard) castlingField(shredder bool) string {
+	outermost := cb.outermostRookFiles()
+	field := ""
+	for _, color := range []bool{true, false} {
+		for _, kingside := range []bool{true, false} {
+			if !cb.Castling.allowed(color, kingside) {
+				continue
+			}
+			file := cb.castlingRookFile(color, kingside)
+			letter := byte('Q')
+			if kingside {
+				letter = 'K'
+			}
+			if shredder || (cb.Chess960 && file != outermost.rookFile(color, kingside)) {
+				letter = byte('A' + file)
+			}
+			if !color {
+				letter += 'a' - 'A'
+			}
+			field += string(letter)
+		}
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
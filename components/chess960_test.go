@@ -0,0 +1,151 @@
+package components
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChess960Positions(t *testing.T) {
+	standard, err := Chess960Position(518)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen := standard.ToFEN(); fen != StartFEN {
+		t.Errorf("position 518 is %s, want %s", fen, StartFEN)
+	}
+	first, err := Chess960Position(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fen := first.ToShredderFEN(); fen != "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1" {
+		t.Errorf("position 0 is %s", fen)
+	}
+
+	seen := make(map[string]int)
+	for index := 0; index < Chess960Positions; index++ {
+		board, err := Chess960Position(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rank := strings.Fields(board.ToFEN())[0][len("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/"):]
+		if other, ok := seen[rank]; ok {
+			t.Fatalf("positions %d and %d are both %s", other, index, rank)
+		}
+		seen[rank] = index
+
+		bishops := strings.IndexByte(rank, 'B') + strings.LastIndexByte(rank, 'B')
+		king := strings.IndexByte(rank, 'K')
+		if bishops%2 == 0 || king < strings.IndexByte(rank, 'R') || king > strings.LastIndexByte(rank, 'R') {
+			t.Errorf("position %d has a bad back rank %s", index, rank)
+		}
+	}
+	if _, err := Chess960Position(Chess960Positions); err == nil {
+		t.Error("no error for position 960")
+	}
+}
+
+func TestChess960FENRoundTrip(t *testing.T) {
+	for _, test := range []struct {
+		xfen, shredder string
+	}{
+		{
+			"bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1",
+			"bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w HFhf - 0 1",
+		},
+		// The inner kingside rooks castle, so X-FEN names their files
+		{
+			"rk3r1r/pppppppp/8/8/8/8/PPPPPPPP/RK3R1R w FQfq - 0 1",
+			"rk3r1r/pppppppp/8/8/8/8/PPPPPPPP/RK3R1R w FAfa - 0 1",
+		},
+		{
+			"1r4kr/8/8/8/8/8/8/1R4KR b Kq - 0 1",
+			"1r4kr/8/8/8/8/8/8/1R4KR b Hb - 0 1",
+		},
+		{
+			StartFEN,
+			"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1",
+		},
+	} {
+		for _, fen := range []string{test.xfen, test.shredder} {
+			board, err := ParseFEN(fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := board.ToFEN(); got != test.xfen {
+				t.Errorf("%s: X-FEN %s, want %s", fen, got, test.xfen)
+			}
+			if got := board.ToShredderFEN(); got != test.shredder {
+				t.Errorf("%s: Shredder-FEN %s, want %s", fen, got, test.shredder)
+			}
+		}
+	}
+}
+
+func TestChess960Perft(t *testing.T) {
+	for _, test := range []struct {
+		fen   string
+		nodes []int
+	}{
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{1, 21, 528, 12189}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{1, 21, 807, 18002}},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{1, 20, 479, 10471}},
+		{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", []int{1, 22, 593, 13440}},
+	} {
+		board, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for depth, want := range test.nodes {
+			if got := perft(Standard{}, &board, depth); got != want {
+				t.Errorf("%s: perft(%d) = %d, want %d", test.fen, depth, got, want)
+			}
+		}
+	}
+}
+
+// TestChess960CastlingSquares castles with the king or rook already on, or
+// moving onto, the other's square
+func TestChess960CastlingSquares(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		fen        string
+		move       string
+		king, rook int
+	}{
+		{"king already on g1", "1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1", "g1h1", 6, 5},
+		{"queenside from g1", "1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1", "g1b1", 2, 3},
+		{"king and rook swap", "4k3/8/8/8/8/8/8/5KR1 w G - 0 1", "f1g1", 6, 5},
+		{"rook on a1, king on b1", "4k3/8/8/8/8/8/8/RK6 w A - 0 1", "b1a1", 2, 3},
+	} {
+		board, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !board.Chess960 {
+			t.Fatalf("%s: not read as Chess960", test.name)
+		}
+		child := playMove(t, Standard{}, &board, test.move)
+		if !board.IsCastling(child.LastMove) {
+			t.Errorf("%s: %s is not castling", test.name, test.move)
+		}
+		if !child.isPieceAt(Coordinates{X: test.king, Y: 0}, true, 'K') || !child.isPieceAt(Coordinates{X: test.rook, Y: 0}, true, 'R') {
+			t.Errorf("%s: king and rook not on files %d and %d: %s", test.name, test.king, test.rook, child.ToFEN())
+		}
+		if child.Castling.WhiteKingside || child.Castling.WhiteQueenside {
+			t.Errorf("%s: white keeps castling rights: %s", test.name, child.ToFEN())
+		}
+		if got, want := firstRankPieces(child), firstRankPieces(&board); got != want {
+			t.Errorf("%s: %d pieces on the first rank, want %d: %s", test.name, got, want, child.ToFEN())
+		}
+	}
+}
+
+func firstRankPieces(board *ChessBoard) int {
+	pieces := 0
+	for _, piece := range board.Board[0] {
+		if piece != nil {
+			pieces++
+		}
+	}
+	return pieces
+}
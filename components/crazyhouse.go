@@ -0,0 +1,155 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+)
+
+const crazyhouseName = "crazyhouse"
+
+// pocketLetters are the pieces a pocket can hold, in the order Pocket counts
+// them. Kings are never captured and promoted pieces return as pawns.
+const pocketLetters = "PNBRQ"
+
+// Pocket counts the captured pieces a side may drop back onto the board,
+// indexed like pocketLetters
+type Pocket [5]int
+
+// Count returns the number of pieces with the given letter in the pocket
+func (pocket Pocket) Count(letter byte) int {
+	if i := strings.IndexByte(pocketLetters, letter); i >= 0 {
+		return pocket[i]
+	}
+	return 0
+}
+
+func (pocket *Pocket) add(letter byte) {
+	if i := strings.IndexByte(pocketLetters, letter); i >= 0 {
+		pocket[i]++
+	}
+}
+
+// String lists the pocket as piece letters, strongest first, e.g. "QNPP"
+func (pocket Pocket) String() string {
+	var builder strings.Builder
+	for i := len(pocketLetters) - 1; i >= 0; i-- {
+		builder.WriteString(strings.Repeat(string(pocketLetters[i]), pocket[i]))
+	}
+	return builder.String()
+}
+
+// Pocket returns the pocket of the given color
+func (cb *ChessBoard) Pocket(color bool) *Pocket {
+	if color {
+		return &cb.Pockets[0]
+	}
+	return &cb.Pockets[1]
+}
+
+// hasPockets reports whether either side holds a piece to drop
+func (cb *ChessBoard) hasPockets() bool {
+	return cb.Pockets != [2]Pocket{}
+}
+
+// Crazyhouse is chess where captured pieces change sides and go to the
+// capturer's pocket, from which they can be dropped instead of moving. A
+// promoted piece goes back to the pocket as a pawn. Legality and the end of
+// the game follow standard chess.
+type Crazyhouse struct {
+	Standard
+}
+
+func (Crazyhouse) Name() string {
+	return crazyhouseName
+}
+
+func (Crazyhouse) StartPosition() ChessBoard {
+	board := Standard{}.StartPosition()
+	board.Variant = crazyhouseName
+	return board
+}
+
+// PseudoLegalMoves adds drops to the standard moves and moves captured
+// pieces into the capturer's pocket. Drops are not captures, so quiescence
+// search never sees them.
+func (Crazyhouse) PseudoLegalMoves(board *ChessBoard, capturesOnly bool) []*ChessBoard {
+	children := board.PseudoLegalMoves(capturesOnly)
+	for _, child := range children {
+		move := child.LastMove
+		if captured := board.CapturedPiece(move); captured != nil {
+			letter := pieceLetter(captured)
+			if board.Promoted[move.To.Y][move.To.X] {
+				letter = 'P'
+			}
+			child.Pocket(board.NextTurn).add(letter)
+		}
+		if move.Promotion != 0 {
+			child.Promoted[move.To.Y][move.To.X] = true
+		}
+	}
+	if !capturesOnly {
+		children = append(children, board.drops()...)
+	}
+	return children
+}
+
+// drops returns a board for every piece in the side to move's pocket dropped
+// on every empty square. Pawns cannot be dropped on the first or last rank.
+func (cb *ChessBoard) drops() []*ChessBoard {
+	var boards []*ChessBoard
+	pocket := cb.Pocket(cb.NextTurn)
+	for i := range pocketLetters {
+		if pocket[i] == 0 {
+			continue
+		}
+		letter := pocketLetters[i]
+		for y, row := range cb.Board {
+			if letter == 'P' && (y == 0 || y == 7) {
+				continue
+			}
+			for x, piece := range row[:cb.Width()] {
+				if piece != nil {
+					continue
+				}
+				newBoard := cb.DeepCopy()
+				newBoard.Board[y][x] = NewPiece(letter, cb.NextTurn)
+				newBoard.Pocket(cb.NextTurn)[i]--
+				newBoard.EnPassant = nil
+				newBoard.LastMove = Move{To: Coordinates{X: x, Y: y}, Drop: letter}
+				newBoard.NextTurn = !cb.NextTurn
+				boards = append(boards, newBoard)
+			}
+		}
+	}
+	return boards
+}
+
+// parseHoldings reads the pockets of a crazyhouse FEN, written as piece
+// letters in brackets after the placement, e.g. "[QNnp]". "-" and an empty
+// pair of brackets mean empty pockets.
+func (cb *ChessBoard) parseHoldings(holdings string) error {
+	cb.Pockets = [2]Pocket{}
+	if holdings == "-" {
+		return nil
+	}
+	for _, char := range holdings {
+		color := char >= 'A' && char <= 'Z'
+		letter := strings.ToUpper(string(char))[0]
+		if strings.IndexByte(pocketLetters, letter) < 0 {
+			return fmt.Errorf("bad holdings %q", holdings)
+		}
+		cb.Pocket(color).add(letter)
+	}
+	return nil
+}
+
+// SetHoldings replaces both pockets with holdings written as in a crazyhouse
+// FEN, White's pieces in upper case, e.g. "QNnp"
+func (cb *ChessBoard) SetHoldings(holdings string) error {
+	return cb.parseHoldings(holdings)
+}
+
+// holdingsField renders the pockets for a crazyhouse FEN, White's first
+func (cb *ChessBoard) holdingsField() string {
+	return cb.Pocket(true).String() + strings.ToLower(cb.Pocket(false).String())
+}
@@ -0,0 +1,62 @@
+package components
+
+import "testing"
+
+func TestCrazyhouseDrops(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		fen   string
+		moves int
+	}{
+		{"knight in pocket", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[N] w KQkq - 0 1", 20 + 32},
+		{"pawn in pocket", "4k3/8/8/8/8/8/8/4K3[P] w - - 0 1", 5 + 48},
+		{"black pocket only", "4k3/8/8/8/8/8/8/4K3[q] w - - 0 1", 5},
+	} {
+		board := parseVariantFEN(t, test.fen, crazyhouseName)
+		moves := LegalMoves(Crazyhouse{}, board)
+		if len(moves) != test.moves {
+			t.Errorf("%s: %d legal moves, want %d", test.name, len(moves), test.moves)
+		}
+		for _, child := range moves {
+			move := child.LastMove
+			if move.Drop == 'P' && (move.To.Y == 0 || move.To.Y == 7) {
+				t.Errorf("%s: pawn dropped on %s", test.name, SquareName(move.To))
+			}
+			if move.Drop != 0 && child.Pocket(true).Count(move.Drop) != board.Pocket(true).Count(move.Drop)-1 {
+				t.Errorf("%s: %s did not empty the pocket", test.name, move.UCI())
+			}
+		}
+	}
+}
+
+func TestCrazyhouseCapturesFillPockets(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		fen    string
+		move   string
+		pocket string
+	}{
+		{"captured queen", "4k3/8/8/q7/8/8/8/R3K3 w - - 0 1", "a1a5", "Q"},
+		{"captured promoted queen", "4k3/8/8/q~7/8/8/8/R3K3 w - - 0 1", "a1a5", "P"},
+		{"captured pawn", "4k3/8/8/p7/8/8/8/R3K3 w - - 0 1", "a1a5", "P"},
+	} {
+		board := parseVariantFEN(t, test.fen, crazyhouseName)
+		child := playMove(t, Crazyhouse{}, board, test.move)
+		if got := child.Pocket(true).String(); got != test.pocket {
+			t.Errorf("%s: white pocket %q, want %q", test.name, got, test.pocket)
+		}
+		if child.Pocket(false).String() != "" {
+			t.Errorf("%s: black pocket %q, want it empty", test.name, child.Pocket(false).String())
+		}
+	}
+
+	// A promotion marks the new piece, so taking it later yields a pawn
+	board := parseVariantFEN(t, "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", crazyhouseName)
+	promoted := playMove(t, Crazyhouse{}, board, "b7b8q")
+	if !promoted.Promoted[7][1] {
+		t.Error("promoted queen on b8 is not marked as promoted")
+	}
+	if fen := promoted.ToFEN(); fen != "1Q~2k3/8/8/8/8/8/8/4K3[] b - - 0 1" {
+		t.Errorf("FEN after promotion %s", fen)
+	}
+}
@@ -0,0 +1,160 @@
+package components
+
+import "fmt"
+
+// Scores are in centipawns. Mate scores count down from MateScore by one for
+// every ply until the mate is delivered.
+const (
+	MateScore     = 100000
+	MateThreshold = MateScore - 1000
+)
+
+var pieceValues = map[byte]int{
+	'P': 100,
+	'N': 320,
+	'B': 330,
+	'R': 500,
+	'Q': 900,
+	'K': 0,
+	'A': 800, // archbishop, bishop and knight
+	'C': 850, // chancellor, rook and knight
+}
+
+// Piece-square tables from White's point of view, written with rank 8 on the
+// first row so they read like a diagram
+var pieceSquareTables = map[byte][8][8]int{
+	'P': {
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{50, 50, 50, 50, 50, 50, 50, 50},
+		{10, 10, 20, 30, 30, 20, 10, 10},
+		{5, 5, 10, 25, 25, 10, 5, 5},
+		{0, 0, 0, 20, 20, 0, 0, 0},
+		{5, -5, -10, 0, 0, -10, -5, 5},
+		{5, 10, 10, -20, -20, 10, 10, 5},
+		{0, 0, 0, 0, 0, 0, 0, 0},
+	},
+	'N': {
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+		{-40, -20, 0, 0, 0, 0, -20, -40},
+		{-30, 0, 10, 15, 15, 10, 0, -30},
+		{-30, 5, 15, 20, 20, 15, 5, -30},
+		{-30, 0, 15, 20, 20, 15, 0, -30},
+		{-30, 5, 10, 15, 15, 10, 5, -30},
+		{-40, -20, 0, 5, 5, 0, -20, -40},
+		{-50, -40, -30, -30, -30, -30, -40, -50},
+	},
+	'B': {
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 10, 10, 5, 0, -10},
+		{-10, 5, 5, 10, 10, 5, 5, -10},
+		{-10, 0, 10, 10, 10, 10, 0, -10},
+		{-10, 10, 10, 10, 10, 10, 10, -10},
+		{-10, 5, 0, 0, 0, 0, 5, -10},
+		{-20, -10, -10, -10, -10, -10, -10, -20},
+	},
+	'R': {
+		{0, 0, 0, 0, 0, 0, 0, 0},
+		{5, 10, 10, 10, 10, 10, 10, 5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{-5, 0, 0, 0, 0, 0, 0, -5},
+		{0, 0, 0, 5, 5, 0, 0, 0},
+	},
+	'Q': {
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+		{-10, 0, 0, 0, 0, 0, 0, -10},
+		{-10, 0, 5, 5, 5, 5, 0, -10},
+		{-5, 0, 5, 5, 5, 5, 0, -5},
+		{0, 0, 5, 5, 5, 5, 0, -5},
+		{-10, 5, 5, 5, 5, 5, 0, -10},
+		{-10, 0, 5, 0, 0, 0, 0, -10},
+		{-20, -10, -10, -5, -5, -10, -10, -20},
+	},
+	'K': {
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-30, -40, -40, -50, -50, -40, -40, -30},
+		{-20, -30, -30, -40, -40, -30, -30, -20},
+		{-10, -20, -20, -20, -20, -20, -20, -10},
+		{20, 20, 0, 0, 0, 0, 20, 20},
+		{20, 30, 10, 0, 0, 10, 30, 20},
+	},
+}
+
+// compoundTables names the piece-square table the compound pieces borrow:
+// their knight moves make them want the centre like a knight
+var compoundTables = map[byte]byte{'A': 'N', 'C': 'N'}
+
+// PieceValue returns the material value of a piece in centipawns
+func PieceValue(piece ChessPiece) int {
+	return pieceValues[pieceLetter(piece)]
+}
+
+// PieceValueOf returns the material value of a piece letter in centipawns
+func PieceValueOf(letter byte) int {
+	return pieceValues[letter]
+}
+
+// Evaluate returns a static material and piece-square score, + white / - black.
+// Pieces in crazyhouse pockets count as material. Wider boards than eight
+// files are squeezed onto the piece-square tables.
+func (cb *ChessBoard) Evaluate() int {
+	score := 0
+	for y, row := range cb.Board {
+		for x, piece := range row {
+			if piece == nil {
+				continue
+			}
+			letter := pieceLetter(piece)
+			table := pieceSquareTables[letter]
+			if borrowed, ok := compoundTables[letter]; ok {
+				table = pieceSquareTables[borrowed]
+			}
+			file := x * 8 / cb.Width()
+			if piece.GetColor() {
+				score += pieceValues[letter] + table[7-y][file]
+			} else {
+				score -= pieceValues[letter] + table[y][file]
+			}
+		}
+	}
+	for i, letter := range []byte(pocketLetters) {
+		score += (cb.Pockets[0][i] - cb.Pockets[1][i]) * pieceValues[letter]
+	}
+	return score
+}
+
+// EvaluateRelative returns Evaluate from the point of view of the side to move
+func (cb *ChessBoard) EvaluateRelative() int {
+	if cb.NextTurn {
+		return cb.Evaluate()
+	}
+	return -cb.Evaluate()
+}
+
+// ParentScore converts a score seen from a child position into the score of
+// the move leading to it, moving mate scores one ply further away
+func ParentScore(childScore int) int {
+	score := -childScore
+	if score > MateThreshold {
+		score--
+	} else if score < -MateThreshold {
+		score++
+	}
+	return score
+}
+
+// FormatScore renders a score in pawns, or as a mate distance in moves
+func FormatScore(score int) string {
+	if score > MateThreshold {
+		return fmt.Sprintf("#%d", (MateScore-score+1)/2)
+	}
+	if score < -MateThreshold {
+		return fmt.Sprintf("-#%d", (MateScore+score)/2)
+	}
+	return fmt.Sprintf("%+.2f", float64(score)/100)
+}
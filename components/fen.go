@@ -0,0 +1,191 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+)
+
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+// ParseFEN reads a FEN string. The move clocks are accepted but not kept, as
+// ChessBoard does not track them. Chess960 positions are recognised from
+// X-FEN and Shredder-FEN castling fields, see parseCastling. Crazyhouse
+// positions are recognised from their holdings, written in brackets after the
+// placement or as a ninth rank, with promoted pieces marked by a "~", and
+// three-check positions from the checks each side still needs, e.g. "3+3",
+// after the en passant square. Ranks of ten files make a Capablanca position.
+func ParseFEN(fen string) (ChessBoard, error) {
+	var board ChessBoard
+	fields := strings.Fields(fen)
+	if len(fields) < 2 {
+		return board, fmt.Errorf("invalid FEN %q: expected at least 2 fields", fen)
+	}
+
+	placement := fields[0]
+	holdings := ""
+	if open := strings.IndexByte(placement, '['); open >= 0 {
+		if !strings.HasSuffix(placement, "]") {
+			return board, fmt.Errorf("invalid FEN %q: unterminated holdings", fen)
+		}
+		placement, holdings = placement[:open], placement[open+1:len(placement)-1]
+		board.Variant = crazyhouseName
+	}
+	ranks := strings.Split(placement, "/")
+	if len(ranks) == 9 {
+		holdings = ranks[8]
+		ranks = ranks[:8]
+		board.Variant = crazyhouseName
+	}
+	if len(ranks) != 8 {
+		return board, fmt.Errorf("invalid FEN %q: expected 8 ranks", fen)
+	}
+	if err := board.parseHoldings(holdings); err != nil {
+		return board, fmt.Errorf("invalid FEN %q: %v", fen, err)
+	}
+	for i, rank := range ranks {
+		y := 7 - i
+		x := 0
+		empty := 0
+		for _, char := range rank {
+			if char >= '0' && char <= '9' {
+				empty = 10*empty + int(char-'0')
+				continue
+			}
+			x += empty
+			empty = 0
+			if char == '~' && x > 0 && board.Board[y][x-1] != nil {
+				board.Promoted[y][x-1] = true
+				continue
+			}
+			letter := strings.ToUpper(string(char))[0]
+			piece := NewPiece(letter, char >= 'A' && char <= 'Z')
+			if piece == nil || x >= MaxFiles {
+				return board, fmt.Errorf("invalid FEN %q: bad rank %q", fen, rank)
+			}
+			board.Board[y][x] = piece
+			x++
+		}
+		x += empty
+		// The first rank sets the width of the board
+		if i == 0 && x == capablancaFiles {
+			board.Files = x
+			board.Variant = capablancaName
+		}
+		if x != board.Width() {
+			return board, fmt.Errorf("invalid FEN %q: rank %q does not have %d files", fen, rank, board.Width())
+		}
+	}
+
+	switch fields[1] {
+	case "w":
+		board.NextTurn = true
+	case "b":
+		board.NextTurn = false
+	default:
+		return board, fmt.Errorf("invalid FEN %q: bad side to move %q", fen, fields[1])
+	}
+
+	if len(fields) > 2 {
+		if err := board.parseCastling(fields[2]); err != nil {
+			return board, fmt.Errorf("invalid FEN %q: %v", fen, err)
+		}
+	}
+
+	if len(fields) > 3 && fields[3] != "-" {
+		square, err := ParseSquare(fields[3])
+		if err != nil {
+			return board, fmt.Errorf("invalid FEN %q: %v", fen, err)
+		}
+		// Kept only when a pawn can actually capture, matching positions
+		// produced by move generation so hashes and stored states agree
+		pawnY := square.Y - 1
+		if !board.NextTurn {
+			pawnY = square.Y + 1
+		}
+		if board.isPieceAt(Coordinates{X: square.X - 1, Y: pawnY}, board.NextTurn, 'P') ||
+			board.isPieceAt(Coordinates{X: square.X + 1, Y: pawnY}, board.NextTurn, 'P') {
+			board.EnPassant = &square
+		}
+	}
+
+	if len(fields) > 4 && strings.Contains(fields[4], "+") {
+		if err := board.parseChecks(fields[4]); err != nil {
+			return board, fmt.Errorf("invalid FEN %q: %v", fen, err)
+		}
+		board.Variant = threeCheckName
+	}
+
+	return board, nil
+}
+
+// ToFEN returns the position in Forsyth-Edwards Notation. Chess960 boards
+// use X-FEN, which only names a rook's file when K or Q would be ambiguous.
+func (cb *ChessBoard) ToFEN() string {
+	return cb.toFEN(false)
+}
+
+// ToShredderFEN returns the position in Shredder-FEN, which names the file
+// of every castling rook
+func (cb *ChessBoard) ToShredderFEN() string {
+	return cb.toFEN(true)
+}
+
+func (cb *ChessBoard) toFEN(shredder bool) string {
+	var builder strings.Builder
+	for y := 7; y >= 0; y-- {
+		empty := 0
+		for x := 0; x < cb.Width(); x++ {
+			piece := cb.Board[y][x]
+			if piece == nil {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fmt.Fprintf(&builder, "%d", empty)
+				empty = 0
+			}
+			builder.WriteByte(pieceFENLetter(piece))
+			if cb.Promoted[y][x] {
+				builder.WriteByte('~')
+			}
+		}
+		if empty > 0 {
+			fmt.Fprintf(&builder, "%d", empty)
+		}
+		if y > 0 {
+			builder.WriteByte('/')
+		}
+	}
+	if cb.Variant == crazyhouseName {
+		builder.WriteString("[" + cb.holdingsField() + "]")
+	}
+
+	if cb.NextTurn {
+		builder.WriteString(" w ")
+	} else {
+		builder.WriteString(" b ")
+	}
+
+	castling := cb.castlingField(shredder)
+	builder.WriteString(castling)
+
+	if cb.EnPassant != nil {
+		builder.WriteString(" " + SquareName(*cb.EnPassant))
+	} else {
+		builder.WriteString(" -")
+	}
+	if cb.Variant == threeCheckName {
+		builder.WriteString(" " + cb.checksField())
+	}
+	builder.WriteString(" 0 1")
+	return builder.String()
+}
+
+// pieceFENLetter returns the FEN letter of a piece, uppercase for White
+func pieceFENLetter(piece ChessPiece) byte {
+	letter := pieceLetter(piece)
+	if !piece.GetColor() {
+		letter += 'a' - 'A'
+	}
+	return letter
+}
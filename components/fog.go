@@ -0,0 +1,121 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Visibility marks the squares one side can see in fog-of-war chess
+type Visibility [Ranks][MaxFiles]bool
+
+// Visible returns the squares the given color sees: those holding its own
+// pieces and every square one of its pieces could move to under the board's
+// variant, drops included. There is no check in fog-of-war, so moves into
+// check count, and pawns only see diagonally when they could capture there.
+func (cb *ChessBoard) Visible(color bool) Visibility {
+	var visible Visibility
+	for y, row := range cb.Board {
+		for x, piece := range row {
+			if piece != nil && piece.GetColor() == color {
+				visible[y][x] = true
+			}
+		}
+	}
+
+	// En passant is only available to the side to move
+	side := cb.DeepCopy()
+	if side.NextTurn != color {
+		side.NextTurn = color
+		side.EnPassant = nil
+	}
+	for _, child := range VariantOf(side).PseudoLegalMoves(side, false) {
+		visible[child.LastMove.To.Y][child.LastMove.To.X] = true
+	}
+	return visible
+}
+
+// FogView is the board as one side sees it in fog-of-war chess
+type FogView struct {
+	Color   bool
+	Board   ChessBoard // the enemy pieces on unseen squares are removed
+	Visible Visibility
+}
+
+// FogView returns the board redacted to what the given color can see. Unseen
+// squares lose their promoted marks as well as their pieces, and the enemy
+// castling rights and crazyhouse pocket are hidden too.
+func (cb *ChessBoard) FogView(color bool) FogView {
+	view := FogView{Color: color, Board: *cb.DeepCopy(), Visible: cb.Visible(color)}
+	for y, row := range view.Board.Board {
+		for x := range row {
+			if !view.Visible[y][x] {
+				view.Board.Board[y][x] = nil
+				view.Board.Promoted[y][x] = false
+			}
+		}
+	}
+	*view.Board.Pocket(!color) = Pocket{}
+	view.Board.Castling.set(!color, true, false)
+	view.Board.Castling.set(!color, false, false)
+	if view.Board.EnPassant != nil && view.Board.NextTurn != color {
+		view.Board.EnPassant = nil
+	}
+	return view
+}
+
+// FEN returns the placement, side to move, castling and en passant fields of
+// the view in Forsyth-Edwards Notation, with every unseen square written as
+// "?" instead of being counted as empty
+func (view FogView) FEN() string {
+	fields := strings.Fields(view.Board.ToFEN())
+	var builder strings.Builder
+	for y := 7; y >= 0; y-- {
+		empty := 0
+		for x := 0; x < view.Board.Width(); x++ {
+			piece := view.Board.Board[y][x]
+			if piece == nil && view.Visible[y][x] {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				fmt.Fprintf(&builder, "%d", empty)
+				empty = 0
+			}
+			if piece == nil {
+				builder.WriteByte('?')
+			} else {
+				builder.WriteByte(pieceFENLetter(piece))
+			}
+		}
+		if empty > 0 {
+			fmt.Fprintf(&builder, "%d", empty)
+		}
+		if y > 0 {
+			builder.WriteByte('/')
+		}
+	}
+	return builder.String() + " " + strings.Join(fields[1:4], " ")
+}
+
+// ToString prints the view like ChessBoard.ToString, with unseen squares
+// shaded
+func (view FogView) ToString() string {
+	fmt.Println(boardBorder(view.Board.Width()))
+	for rNum, row := range view.Board.Board {
+		fmt.Print(rNum, "-|")
+		for x, piece := range row[:view.Board.Width()] {
+			switch {
+			case !view.Visible[rNum][x]:
+				fmt.Print(" ### ")
+			case piece != nil:
+				fmt.Print(piece.ToString())
+			default:
+				fmt.Print("     ")
+			}
+		}
+		fmt.Print("|\n\n")
+	}
+	fmt.Println(boardBorder(view.Board.Width()))
+	fmt.Println(fileLabels(view.Board.Width()))
+	return ""
+}
@@ -0,0 +1,53 @@
+package components
+
+import "testing"
+
+func TestFogViewHidesSquareMetadata(t *testing.T) {
+	// The promoted black queen on h8 and Black's pocket are out of White's
+	// sight; the white king sees d1, d2, e2, f2 and f1
+	board, err := ParseFEN("4k2q~/8/8/8/8/8/8/4K3[Nr] w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	view := board.FogView(true)
+	if view.Visible[7][7] {
+		t.Fatal("h8 is visible to White")
+	}
+	if view.Board.Board[7][7] != nil || view.Board.Promoted[7][7] {
+		t.Error("the hidden h8 square still holds its piece or promoted mark")
+	}
+	if got := view.Board.Pocket(false).String(); got != "" {
+		t.Errorf("Black's pocket %q shows through the fog", got)
+	}
+	if got := view.Board.Pocket(true).String(); got != "N" {
+		t.Errorf("White's own pocket %q, want N", got)
+	}
+	if !board.Promoted[7][7] || board.Pocket(false).String() != "R" {
+		t.Error("FogView changed the board it was made from")
+	}
+}
+
+func TestFogVisibleDropSquares(t *testing.T) {
+	// A knight in hand can be dropped on any empty square, so White sees
+	// every one of them
+	board, err := ParseFEN("4k3/8/8/8/8/8/8/4K3[N] w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	visible := board.Visible(true)
+	for y := 0; y < Ranks; y++ {
+		for x := 0; x < 8; x++ {
+			if board.Board[y][x] == nil && !visible[y][x] {
+				t.Errorf("empty %s is not visible with a knight to drop", SquareName(Coordinates{X: x, Y: y}))
+			}
+		}
+	}
+
+	// Without pieces in hand only the king's squares are seen
+	board.Variant = standardName
+	board.Pockets = [2]Pocket{}
+	visible = board.Visible(true)
+	if visible[4][4] {
+		t.Error("e5 is visible without a piece to drop")
+	}
+}
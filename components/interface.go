@@ -2,6 +2,32 @@ package components
 
 type ChessPiece interface {
 	GetPossibleMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard
+	GetCaptureMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard
 	GetColor() bool
 	ToString() string
 }
+
+// NewPiece creates a piece from its letter (P, N, B, R, Q, K, or A and C
+// for the archbishop and chancellor)
+func NewPiece(letter byte, color bool) ChessPiece {
+	switch letter {
+	case 'P':
+		return Pawn{Color: color}
+	case 'N':
+		return Knight{Color: color}
+	case 'B':
+		return Bishop{Color: color}
+	case 'R':
+		return Rook{Color: color}
+	case 'Q':
+		return Queen{Color: color}
+	case 'K':
+		return King{Color: color}
+	case 'A':
+		return Archbishop{Color: color}
+	case 'C':
+		return Chancellor{Color: color}
+	default:
+		return nil
+	}
+}
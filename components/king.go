@@ -32,7 +32,7 @@ func (king King) GetPossibleMoves(board ChessBoard, position Coordinates, inChec
 
 			// Only check if the move is safe if we're not already checking for check
 			if !inCheck {
-				if !newBoard.WouldLeaveKingInCheck(king.Color, cache, 0) {
+				if !board.withExplosion(newBoard).WouldLeaveKingInCheck(king.Color, cache, 0) {
 					possibleBoards = append(possibleBoards, newBoard)
 				}
 			} else {
@@ -41,6 +41,115 @@ func (king King) GetPossibleMoves(board ChessBoard, position Coordinates, inChec
 		}
 	}
 
+	possibleBoards = append(possibleBoards, king.castlingMoves(board, position)...)
+
+	return possibleBoards
+}
+
+// castlingMoves returns the castling moves still available. The king may not
+// castle out of, through or into check. Both the standard and the Chess960
+// rules are covered: the king ends on the g or c file and the rook next to
+// it, and every square either of them crosses must be empty apart from the
+// two of them. On a Capablanca board the king ends on the i or c file.
+func (king King) castlingMoves(board ChessBoard, position Coordinates) []*ChessBoard {
+	var possibleBoards []*ChessBoard
+
+	rank := 0
+	kingside, queenside := board.Castling.WhiteKingside, board.Castling.WhiteQueenside
+	if !king.Color {
+		rank = 7
+		kingside, queenside = board.Castling.BlackKingside, board.Castling.BlackQueenside
+	}
+	if position.Y != rank || (!board.Chess960 && position.X != board.standardKingFile()) || board.isKingSquareAttacked(position, king.Color) {
+		return nil
+	}
+
+	// The squares the king crosses are checked without it, so it cannot
+	// shield them from an attacker on its own rank
+	withoutKing := board.DeepCopy()
+	withoutKing.Board[rank][position.X] = nil
+
+	sides := []struct {
+		allowed bool
+		rookX   int
+		kingTo  int
+		rookTo  int
+	}{
+		{kingside, board.castlingRookFile(king.Color, true), board.Width() - 2, board.Width() - 3},
+		{queenside, board.castlingRookFile(king.Color, false), 2, 3},
+	}
+
+	for _, side := range sides {
+		if !side.allowed || !board.isPieceAt(Coordinates{X: side.rookX, Y: rank}, king.Color, 'R') ||
+			(side.kingTo > 2) != (side.rookX > position.X) {
+			continue
+		}
+
+		from := min(position.X, side.rookX, side.kingTo, side.rookTo)
+		to := max(position.X, side.rookX, side.kingTo, side.rookTo)
+		clear := true
+		for x := from; x <= to; x++ {
+			if x != position.X && x != side.rookX && !board.IsEmpty(Coordinates{X: x, Y: rank}) {
+				clear = false
+			}
+		}
+		step := 1
+		if side.kingTo < position.X {
+			step = -1
+		}
+		// The destination is left to the legality check, which sees the rook
+		// in its new place
+		for x := position.X; clear && x != side.kingTo; {
+			x += step
+			if x != side.kingTo && withoutKing.isKingSquareAttacked(Coordinates{X: x, Y: rank}, king.Color) {
+				clear = false
+			}
+		}
+		if !clear {
+			continue
+		}
+
+		newBoard := board.DeepCopy()
+		kingPiece, rook := newBoard.Board[rank][position.X], newBoard.Board[rank][side.rookX]
+		newBoard.Board[rank][position.X] = nil
+		newBoard.Board[rank][side.rookX] = nil
+		newBoard.Board[rank][side.kingTo] = kingPiece
+		newBoard.Board[rank][side.rookTo] = rook
+		newBoard.EnPassant = nil
+		newBoard.clearCastlingFor(king.Color)
+
+		// Chess960 writes castling as the king taking its rook, which keeps
+		// it apart from king moves and from standing still
+		newBoard.LastMove = Move{From: position, To: Coordinates{X: side.kingTo, Y: rank}}
+		if board.Chess960 {
+			newBoard.LastMove.To = Coordinates{X: side.rookX, Y: rank}
+		}
+		possibleBoards = append(possibleBoards, newBoard)
+	}
+
+	return possibleBoards
+}
+
+// GetCaptureMoves returns only the moves that take an enemy piece
+func (king King) GetCaptureMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard {
+	var possibleBoards []*ChessBoard
+
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			move := Coordinates{X: position.X + dx, Y: position.Y + dy}
+			if (dx == 0 && dy == 0) || !board.IsEnemy(move, king.Color) {
+				continue
+			}
+			newBoard := board.DeepCopy()
+			newBoard.MovePiece(position, move)
+
+			// Same convention as GetPossibleMoves: inCheck skips the safety check
+			if inCheck || !board.withExplosion(newBoard).WouldLeaveKingInCheck(king.Color, nil, 0) {
+				possibleBoards = append(possibleBoards, newBoard)
+			}
+		}
+	}
+
 	return possibleBoards
 }
 
@@ -0,0 +1,36 @@
+package components
+
+const kingOfTheHillName = "kingofthehill"
+
+// KingOfTheHill is standard chess that a side also wins by bringing its king
+// to one of the four centre squares
+type KingOfTheHill struct {
+	Standard
+}
+
+func (KingOfTheHill) Name() string {
+	return kingOfTheHillName
+}
+
+func (KingOfTheHill) StartPosition() ChessBoard {
+	board := Standard{}.StartPosition()
+	board.Variant = kingOfTheHillName
+	return board
+}
+
+// Decided is a win for a side whose king stands on d4, e4, d5 or e5
+func (KingOfTheHill) Decided(board *ChessBoard) Result {
+	for _, color := range []bool{!board.NextTurn, board.NextTurn} {
+		if king, found := board.FindKing(color); found && king.X >= 3 && king.X <= 4 && king.Y >= 3 && king.Y <= 4 {
+			return winFor(color)
+		}
+	}
+	return Ongoing
+}
+
+func (variant KingOfTheHill) Result(board *ChessBoard, moves []*ChessBoard) Result {
+	if result := variant.Decided(board); result != Ongoing {
+		return result
+	}
+	return variant.Standard.Result(board, moves)
+}
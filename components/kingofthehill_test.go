@@ -0,0 +1,32 @@
+package components
+
+import "testing"
+
+func TestKingOfTheHillCentre(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		fen  string
+		want Result
+	}{
+		{"white king on d4", "k7/8/8/8/3K4/8/8/8 b - - 0 1", WhiteWins},
+		{"white king on e4", "k7/8/8/8/4K3/8/8/8 b - - 0 1", WhiteWins},
+		{"white king on d5", "k7/8/8/3K4/8/8/8/8 b - - 0 1", WhiteWins},
+		{"black king on e5", "8/8/8/4k3/8/8/8/K7 w - - 0 1", BlackWins},
+		{"next to the centre", "k7/8/8/8/2K5/8/8/8 b - - 0 1", Ongoing},
+		{"checkmate still wins", "7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", WhiteWins},
+	} {
+		board := parseVariantFEN(t, test.fen, kingOfTheHillName)
+		if got := GameResult(KingOfTheHill{}, board); got != test.want {
+			t.Errorf("%s: result %s, want %s", test.name, got, test.want)
+		}
+	}
+
+	// Stepping into the centre wins and ends the game
+	board := parseVariantFEN(t, "k7/8/8/8/8/4K3/8/8 w - - 0 1", kingOfTheHillName)
+	if child := playMove(t, KingOfTheHill{}, board, "e3e4"); GameResult(KingOfTheHill{}, child) != WhiteWins || len(LegalMoves(KingOfTheHill{}, child)) != 0 {
+		t.Error("e3e4 does not win")
+	}
+	if child := playMove(t, KingOfTheHill{}, board, "e3f3"); GameResult(KingOfTheHill{}, child) != Ongoing {
+		t.Error("e3f3 ends the game")
+	}
+}
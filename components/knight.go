@@ -32,7 +32,37 @@ func (knight Knight) GetPossibleMoves(board ChessBoard, position Coordinates, in
 
 	// Filter boards if inCheck is true
 	if inCheck {
-		possibleBoards = filterBoardsInCheck(possibleBoards, knight.Color)
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, knight.Color)
+	}
+
+	return possibleBoards
+}
+
+// GetCaptureMoves returns only the moves that take an enemy piece
+func (knight Knight) GetCaptureMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard {
+	var possibleBoards []*ChessBoard
+
+	moves := []Coordinates{
+		{X: position.X + 2, Y: position.Y + 1},
+		{X: position.X + 2, Y: position.Y - 1},
+		{X: position.X - 2, Y: position.Y + 1},
+		{X: position.X - 2, Y: position.Y - 1},
+		{X: position.X + 1, Y: position.Y + 2},
+		{X: position.X + 1, Y: position.Y - 2},
+		{X: position.X - 1, Y: position.Y + 2},
+		{X: position.X - 1, Y: position.Y - 2},
+	}
+
+	for _, move := range moves {
+		if board.IsEnemy(move, knight.Color) {
+			newBoard := board.DeepCopy()
+			newBoard.MovePiece(position, move)
+			possibleBoards = append(possibleBoards, newBoard)
+		}
+	}
+
+	if inCheck {
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, knight.Color)
 	}
 
 	return possibleBoards
@@ -0,0 +1,79 @@
+package components
+
+import "fmt"
+
+// Move describes a single move by the squares it leaves and lands on. The
+// zero Move (a1 to a1) means no move. A crazyhouse drop only has To.
+type Move struct {
+	From      Coordinates
+	To        Coordinates
+	Promotion byte // piece letter a pawn promotes to, 0 otherwise
+	Drop      byte // piece letter dropped from the pocket, 0 otherwise
+}
+
+func (move Move) IsNull() bool {
+	return move.Drop == 0 && move.From == move.To
+}
+
+// UCI returns the move in long algebraic notation, e.g. "e2e4" or "e7e8q".
+// Drops are written as the piece letter and square, e.g. "N@f3".
+func (move Move) UCI() string {
+	if move.IsNull() {
+		return "0000"
+	}
+	if move.Drop != 0 {
+		return string(move.Drop) + "@" + SquareName(move.To)
+	}
+	text := SquareName(move.From) + SquareName(move.To)
+	if move.Promotion != 0 {
+		text += string(move.Promotion + 'a' - 'A')
+	}
+	return text
+}
+
+// SquareName returns the algebraic name of a square. Y 0 is White's back rank.
+func SquareName(position Coordinates) string {
+	return fmt.Sprintf("%c%d", 'a'+position.X, position.Y+1)
+}
+
+// ParseSquare converts an algebraic square name such as "e4" into coordinates.
+// Files up to the widest board are accepted; callers check the square lies
+// on their board.
+func ParseSquare(name string) (Coordinates, error) {
+	if len(name) != 2 || name[0] < 'a' || name[0] >= 'a'+MaxFiles || name[1] < '1' || name[1] >= '1'+Ranks {
+		return Coordinates{}, fmt.Errorf("invalid square %q", name)
+	}
+	return Coordinates{X: int(name[0] - 'a'), Y: int(name[1] - '1')}, nil
+}
+
+// CapturedPiece returns the piece a move takes on this board, including a pawn
+// taken en passant, or nil for a quiet move or a drop. A Chess960 castling
+// move lands on its own rook and captures nothing.
+func (cb *ChessBoard) CapturedPiece(move Move) ChessPiece {
+	if move.Drop != 0 {
+		return nil
+	}
+	mover := cb.Board[move.From.Y][move.From.X]
+	if captured := cb.Board[move.To.Y][move.To.X]; captured != nil {
+		if mover != nil && captured.GetColor() == mover.GetColor() {
+			return nil
+		}
+		return captured
+	}
+	if mover != nil && pieceLetter(mover) == 'P' && move.From.X != move.To.X {
+		return cb.Board[move.From.Y][move.To.X]
+	}
+	return nil
+}
+
+// ApplyUCIMove plays a move given in long algebraic notation, returning the
+// resulting board with NextTurn handed over. Only moves legal under the
+// board's variant are accepted.
+func (cb *ChessBoard) ApplyUCIMove(text string) (*ChessBoard, error) {
+	for _, child := range LegalMoves(VariantOf(cb), cb) {
+		if child.LastMove.UCI() == text {
+			return child, nil
+		}
+	}
+	return nil, fmt.Errorf("illegal move %q", text)
+}
@@ -20,14 +20,11 @@ func (pawn Pawn) GetPossibleMoves(board ChessBoard, position Coordinates, inChec
 	// fmt.Println("FROM: ", Coordinates{X: position.X, Y: position.Y})
 	// fmt.Println("TO: ", oneStepForward)
 	if board.IsEmpty(oneStepForward) {
-		newBoard := board.DeepCopy()
-		newBoard.MovePiece(position, oneStepForward)
-		possibleBoards = append(possibleBoards, newBoard)
-		// TODO: Handle Promotion Scenario
+		possibleBoards = append(possibleBoards, pawn.moveTo(board, position, oneStepForward)...)
 	}
 
-	// Forward 2 (only if first move)
-	if (pawn.Color && position.Y == 1) || (!pawn.Color && position.Y == 6) {
+	// Forward 2 (only if first move and the square in between is free)
+	if ((pawn.Color && position.Y == 1) || (!pawn.Color && position.Y == 6)) && board.IsEmpty(oneStepForward) {
 		twoStepsForward := Coordinates{X: position.X, Y: position.Y + 2*direction}
 
 		// fmt.Println("FROM: ", Coordinates{X: position.X, Y: position.Y})
@@ -35,41 +32,33 @@ func (pawn Pawn) GetPossibleMoves(board ChessBoard, position Coordinates, inChec
 		if board.IsEmpty(twoStepsForward) {
 			newBoard := board.DeepCopy()
 			newBoard.MovePiece(position, twoStepsForward)
+
+			// Only record the en passant square when an enemy pawn could use it
+			for _, dx := range []int{-1, 1} {
+				if newBoard.isPieceAt(Coordinates{X: twoStepsForward.X + dx, Y: twoStepsForward.Y}, !pawn.Color, 'P') {
+					newBoard.EnPassant = &oneStepForward
+				}
+			}
 			possibleBoards = append(possibleBoards, newBoard)
 		}
 	}
 
-	// Capture diagonally left
-	diagonalLeft := Coordinates{X: position.X - 1, Y: position.Y + direction}
-	if board.IsEnemy(diagonalLeft, pawn.Color) {
-		newBoard := board.DeepCopy()
-		newBoard.MovePiece(position, diagonalLeft)
-		possibleBoards = append(possibleBoards, newBoard)
-	}
-
-	// Capture diagonally right
-	diagonalRight := Coordinates{X: position.X + 1, Y: position.Y + direction}
-	if board.IsEnemy(diagonalRight, pawn.Color) {
-		newBoard := board.DeepCopy()
-		newBoard.MovePiece(position, diagonalRight)
-		possibleBoards = append(possibleBoards, newBoard)
-	}
-
-	// TODO: handle En-Passant scenario
+	possibleBoards = append(possibleBoards, pawn.captures(board, position)...)
 
 	// Filter boards if inCheck is true
 	if inCheck {
-		possibleBoards = filterBoardsInCheck(possibleBoards, pawn.Color)
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, pawn.Color)
 	}
 
 	return possibleBoards
 }
 
-// Helper function to filter boards that would leave the king in check
-func filterBoardsInCheck(boards []*ChessBoard, color bool) []*ChessBoard {
+// Helper function to filter the boards reached from parent that would leave
+// the king in check, after the explosion of an atomic capture
+func filterBoardsInCheck(parent *ChessBoard, boards []*ChessBoard, color bool) []*ChessBoard {
 	var validBoards []*ChessBoard
 	for _, board := range boards {
-		if !board.WouldLeaveKingInCheck(color, nil, 0) {
+		if !parent.withExplosion(board).WouldLeaveKingInCheck(color, nil, 0) {
 			validBoards = append(validBoards, board)
 		}
 	}
@@ -87,3 +76,78 @@ func (pawn Pawn) ToString() string {
 		return " B P "
 	}
 }
+
+// GetCaptureMoves returns diagonal captures plus pushes that promote, the
+// moves quiescence search looks at
+func (pawn Pawn) GetCaptureMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard {
+	possibleBoards := pawn.captures(board, position)
+
+	oneStepForward := Coordinates{X: position.X, Y: position.Y + pawn.direction()}
+	if pawn.isPromotionRank(oneStepForward.Y) && board.IsEmpty(oneStepForward) {
+		possibleBoards = append(possibleBoards, pawn.moveTo(board, position, oneStepForward)...)
+	}
+
+	if inCheck {
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, pawn.Color)
+	}
+
+	return possibleBoards
+}
+
+func (pawn Pawn) captures(board ChessBoard, position Coordinates) []*ChessBoard {
+	var possibleBoards []*ChessBoard
+	direction := pawn.direction()
+
+	// Capture diagonally left
+	diagonalLeft := Coordinates{X: position.X - 1, Y: position.Y + direction}
+	if board.IsEnemy(diagonalLeft, pawn.Color) {
+		possibleBoards = append(possibleBoards, pawn.moveTo(board, position, diagonalLeft)...)
+	}
+
+	// Capture diagonally right
+	diagonalRight := Coordinates{X: position.X + 1, Y: position.Y + direction}
+	if board.IsEnemy(diagonalRight, pawn.Color) {
+		possibleBoards = append(possibleBoards, pawn.moveTo(board, position, diagonalRight)...)
+	}
+
+	// En passant, removing the pawn that just passed by
+	if target := board.EnPassant; target != nil && (*target == diagonalLeft || *target == diagonalRight) {
+		newBoard := board.DeepCopy()
+		newBoard.MovePiece(position, *target)
+		newBoard.Board[position.Y][target.X] = nil
+		possibleBoards = append(possibleBoards, newBoard)
+	}
+
+	return possibleBoards
+}
+
+// moveTo moves the pawn, producing one board per promotion piece when it
+// reaches the last rank
+func (pawn Pawn) moveTo(board ChessBoard, from, to Coordinates) []*ChessBoard {
+	if !pawn.isPromotionRank(to.Y) {
+		newBoard := board.DeepCopy()
+		newBoard.MovePiece(from, to)
+		return []*ChessBoard{newBoard}
+	}
+
+	var possibleBoards []*ChessBoard
+	for _, letter := range []byte{'Q', 'R', 'B', 'N'} {
+		newBoard := board.DeepCopy()
+		newBoard.MovePiece(from, to)
+		newBoard.Board[to.Y][to.X] = NewPiece(letter, pawn.Color)
+		newBoard.LastMove.Promotion = letter
+		possibleBoards = append(possibleBoards, newBoard)
+	}
+	return possibleBoards
+}
+
+func (pawn Pawn) direction() int {
+	if pawn.Color {
+		return 1
+	}
+	return -1
+}
+
+func (pawn Pawn) isPromotionRank(y int) bool {
+	return (pawn.Color && y == 7) || (!pawn.Color && y == 0)
+}
@@ -22,7 +22,7 @@ func (queen Queen) GetPossibleMoves(board ChessBoard, position Coordinates, inCh
 	}
 
 	for _, direction := range directions {
-		for i := 1; i < 8; i++ {
+		for i := 1; ; i++ {
 			newPosition := Coordinates{X: position.X + i*direction.X, Y: position.Y + i*direction.Y}
 			if !board.IsWithinBounds(newPosition) {
 				break
@@ -44,7 +44,47 @@ func (queen Queen) GetPossibleMoves(board ChessBoard, position Coordinates, inCh
 
 	// Filter boards if inCheck is true
 	if inCheck {
-		possibleBoards = filterBoardsInCheck(possibleBoards, queen.Color)
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, queen.Color)
+	}
+
+	return possibleBoards
+}
+
+// GetCaptureMoves returns only the moves that take an enemy piece
+func (queen Queen) GetCaptureMoves(board ChessBoard, position Coordinates, inCheck bool) []*ChessBoard {
+	var possibleBoards []*ChessBoard
+
+	directions := []Coordinates{
+		{X: 1, Y: 0},
+		{X: -1, Y: 0},
+		{X: 0, Y: 1},
+		{X: 0, Y: -1},
+		{X: 1, Y: 1},
+		{X: 1, Y: -1},
+		{X: -1, Y: 1},
+		{X: -1, Y: -1},
+	}
+
+	for _, direction := range directions {
+		for i := 1; ; i++ {
+			newPosition := Coordinates{X: position.X + i*direction.X, Y: position.Y + i*direction.Y}
+			if !board.IsWithinBounds(newPosition) {
+				break
+			}
+			if board.IsEmpty(newPosition) {
+				continue
+			}
+			if board.IsEnemy(newPosition, queen.Color) {
+				newBoard := board.DeepCopy()
+				newBoard.MovePiece(position, newPosition)
+				possibleBoards = append(possibleBoards, newBoard)
+			}
+			break
+		}
+	}
+
+	if inCheck {
+		possibleBoards = filterBoardsInCheck(&board, possibleBoards, queen.Color)
 	}
 
 	return possibleBoards
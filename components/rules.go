@@ -0,0 +1,137 @@
+package components
+
+import "strings"
+
+// pieceLetter returns the single letter type of a piece (P, N, B, R, Q, K,
+// or A and C for the archbishop and chancellor)
+func pieceLetter(piece ChessPiece) byte {
+	return piece.ToString()[3]
+}
+
+// FindKing returns the position of the king of the given color
+func (cb *ChessBoard) FindKing(color bool) (Coordinates, bool) {
+	for y := 0; y < Ranks; y++ {
+		for x := 0; x < cb.Width(); x++ {
+			piece := cb.Board[y][x]
+			if piece != nil && piece.GetColor() == color && pieceLetter(piece) == 'K' {
+				return Coordinates{X: x, Y: y}, true
+			}
+		}
+	}
+	return Coordinates{}, false
+}
+
+// IsSquareAttacked reports whether any piece of the given color attacks the
+// square. Unlike ComputeAttacks it stops at the first attacker found.
+func (cb *ChessBoard) IsSquareAttacked(position Coordinates, byColor bool) bool {
+	// Pawns attack diagonally forward, so look one rank behind the square
+	direction := 1
+	if !byColor {
+		direction = -1
+	}
+	for _, dx := range []int{-1, 1} {
+		if cb.isPieceAt(Coordinates{X: position.X + dx, Y: position.Y - direction}, byColor, 'P') {
+			return true
+		}
+	}
+
+	knightMoves := []Coordinates{
+		{X: 2, Y: 1}, {X: 2, Y: -1}, {X: -2, Y: 1}, {X: -2, Y: -1},
+		{X: 1, Y: 2}, {X: 1, Y: -2}, {X: -1, Y: 2}, {X: -1, Y: -2},
+	}
+	for _, move := range knightMoves {
+		square := Coordinates{X: position.X + move.X, Y: position.Y + move.Y}
+		if cb.isPieceAt(square, byColor, 'N') || cb.isPieceAt(square, byColor, 'A') || cb.isPieceAt(square, byColor, 'C') {
+			return true
+		}
+	}
+
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			if (dx != 0 || dy != 0) && cb.isPieceAt(Coordinates{X: position.X + dx, Y: position.Y + dy}, byColor, 'K') {
+				return true
+			}
+		}
+	}
+
+	diagonals := []Coordinates{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+	straights := []Coordinates{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	return cb.isAttackedAlong(position, byColor, diagonals, "BQA") ||
+		cb.isAttackedAlong(position, byColor, straights, "RQC")
+}
+
+// isAttackedAlong walks each ray from position and reports whether the first
+// piece met is one of the given color's sliders moving along that kind of ray
+func (cb *ChessBoard) isAttackedAlong(position Coordinates, byColor bool, directions []Coordinates, sliders string) bool {
+	for _, dir := range directions {
+		for i := 1; ; i++ {
+			newPos := Coordinates{X: position.X + i*dir.X, Y: position.Y + i*dir.Y}
+			if !cb.IsWithinBounds(newPos) {
+				break
+			}
+			piece := cb.Board[newPos.Y][newPos.X]
+			if piece == nil {
+				continue
+			}
+			if piece.GetColor() == byColor && strings.IndexByte(sliders, pieceLetter(piece)) >= 0 {
+				return true
+			}
+			break
+		}
+	}
+	return false
+}
+
+func (cb *ChessBoard) isPieceAt(position Coordinates, color bool, letter byte) bool {
+	if !cb.IsWithinBounds(position) {
+		return false
+	}
+	piece := cb.Board[position.Y][position.X]
+	return piece != nil && piece.GetColor() == color && pieceLetter(piece) == letter
+}
+
+// IsInCheck reports whether the king of the given color is attacked
+func (cb *ChessBoard) IsInCheck(color bool) bool {
+	kingPosition, found := cb.FindKing(color)
+	if !found {
+		return false
+	}
+	return cb.IsSquareAttacked(kingPosition, !color)
+}
+
+// IsLegalPosition reports whether the side that just moved left its own king
+// safe. Exploration stores pseudo-legal children, so positions read back from
+// the database are not guaranteed to pass this.
+func (cb *ChessBoard) IsLegalPosition() bool {
+	return !cb.IsInCheck(!cb.NextTurn)
+}
+
+// PseudoLegalMoves returns every position reachable by a move of the side to
+// move's pieces, including moves that leave its own king in check, with
+// NextTurn already handed to the opponent. capturesOnly limits them to
+// captures and promotions.
+func (cb *ChessBoard) PseudoLegalMoves(capturesOnly bool) []*ChessBoard {
+	var boards []*ChessBoard
+	for y, row := range cb.Board {
+		for x, piece := range row {
+			if piece == nil || piece.GetColor() != cb.NextTurn {
+				continue
+			}
+			// inCheck set skips the king's own safety check, which follows
+			// standard rules; it means the opposite to the other pieces
+			position := Coordinates{X: x, Y: y}
+			skipSafety := pieceLetter(piece) == 'K'
+			var possibleBoards []*ChessBoard
+			if capturesOnly {
+				possibleBoards = piece.GetCaptureMoves(*cb, position, skipSafety)
+			} else {
+				possibleBoards = piece.GetPossibleMoves(*cb, position, skipSafety)
+			}
+			for _, newBoard := range possibleBoards {
+				newBoard.NextTurn = !cb.NextTurn
+				boards = append(boards, newBoard)
+			}
+		}
+	}
+	return boards
+}
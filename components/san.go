@@ -0,0 +1,117 @@
+package components
+
+// MoveFlags describes what kind of move a Move is on the board it is played
+// on
+type MoveFlags uint8
+
+const (
+	FlagCapture MoveFlags = 1 << iota
+	FlagEnPassant
+	FlagCastling
+	FlagPromotion
+	FlagDrop
+)
+
+// Flags returns the kind of a move on this board. An en passant capture has
+// both FlagCapture and FlagEnPassant.
+func (cb *ChessBoard) Flags(move Move) MoveFlags {
+	if move.Drop != 0 {
+		return FlagDrop
+	}
+	if cb.IsCastling(move) {
+		return FlagCastling
+	}
+
+	var flags MoveFlags
+	if cb.CapturedPiece(move) != nil {
+		flags |= FlagCapture
+		if cb.Board[move.To.Y][move.To.X] == nil {
+			flags |= FlagEnPassant
+		}
+	}
+	if move.Promotion != 0 {
+		flags |= FlagPromotion
+	}
+	return flags
+}
+
+// SANs returns the standard algebraic notation of every legal move on this
+// board under its variant, e.g. "Nf3", "exd5", "O-O", "e8=Q+" or "N@f3".
+// Pseudo-legal moves that are not legal have no name.
+func (cb *ChessBoard) SANs() map[Move]string {
+	variant := VariantOf(cb)
+	children := LegalMoves(variant, cb)
+
+	names := make(map[Move]string, len(children))
+	for _, child := range children {
+		name := cb.sanWithout(child.LastMove, children)
+		if variant.InCheck(child) {
+			if len(LegalMoves(variant, child)) == 0 {
+				name += "#"
+			} else {
+				name += "+"
+			}
+		}
+		names[child.LastMove] = name
+	}
+	return names
+}
+
+// sanWithout names a move without its check suffix. Moves of the same piece
+// to the same square among children are told apart by file, rank or both.
+func (cb *ChessBoard) sanWithout(move Move, children []*ChessBoard) string {
+	if move.Drop != 0 {
+		return string(move.Drop) + "@" + SquareName(move.To)
+	}
+	if cb.IsCastling(move) {
+		if move.To.X > move.From.X {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+
+	letter := pieceLetter(cb.Board[move.From.Y][move.From.X])
+	capture := cb.CapturedPiece(move) != nil
+
+	var name string
+	if letter == 'P' {
+		if capture {
+			name = SquareName(move.From)[:1] + "x"
+		}
+		name += SquareName(move.To)
+		if move.Promotion != 0 {
+			name += "=" + string(move.Promotion)
+		}
+		return name
+	}
+
+	sameFile, sameRank, ambiguous := false, false, false
+	for _, child := range children {
+		other := child.LastMove
+		if other.Drop != 0 || other.To != move.To || other.From == move.From || cb.IsCastling(other) {
+			continue
+		}
+		if pieceLetter(cb.Board[other.From.Y][other.From.X]) != letter {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || other.From.X == move.From.X
+		sameRank = sameRank || other.From.Y == move.From.Y
+	}
+
+	name = string(letter)
+	from := SquareName(move.From)
+	switch {
+	case !ambiguous:
+	case !sameFile:
+		name += from[:1]
+	case !sameRank:
+		name += from[1:]
+	default:
+		name += from
+	}
+	if capture {
+		name += "x"
+	}
+	return name + SquareName(move.To)
+}
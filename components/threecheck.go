@@ -0,0 +1,86 @@
+package components
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const threeCheckName = "threecheck"
+
+// checksToWin is the number of checks that wins a three-check game
+const checksToWin = 3
+
+// ThreeCheck is standard chess that a side also wins by giving its third
+// check
+type ThreeCheck struct {
+	Standard
+}
+
+func (ThreeCheck) Name() string {
+	return threeCheckName
+}
+
+func (ThreeCheck) StartPosition() ChessBoard {
+	board := Standard{}.StartPosition()
+	board.Variant = threeCheckName
+	return board
+}
+
+// PseudoLegalMoves counts the checks the moves give
+func (ThreeCheck) PseudoLegalMoves(board *ChessBoard, capturesOnly bool) []*ChessBoard {
+	children := board.PseudoLegalMoves(capturesOnly)
+	for _, child := range children {
+		if child.IsInCheck(child.NextTurn) {
+			*child.ChecksGiven(board.NextTurn)++
+		}
+	}
+	return children
+}
+
+// Decided is a win for a side that has given three checks
+func (ThreeCheck) Decided(board *ChessBoard) Result {
+	for _, color := range []bool{true, false} {
+		if *board.ChecksGiven(color) >= checksToWin {
+			return winFor(color)
+		}
+	}
+	return Ongoing
+}
+
+func (variant ThreeCheck) Result(board *ChessBoard, moves []*ChessBoard) Result {
+	if result := variant.Decided(board); result != Ongoing {
+		return result
+	}
+	return variant.Standard.Result(board, moves)
+}
+
+// ChecksGiven returns the number of checks the given color has given
+func (cb *ChessBoard) ChecksGiven(color bool) *int {
+	if color {
+		return &cb.Checks[0]
+	}
+	return &cb.Checks[1]
+}
+
+// parseChecks reads the three-check field of a FEN, the checks each side
+// still needs to win written as "3+3", White's first
+func (cb *ChessBoard) parseChecks(field string) error {
+	remaining := strings.Split(field, "+")
+	if len(remaining) != 2 {
+		return fmt.Errorf("bad check count %q", field)
+	}
+	for i, color := range []bool{true, false} {
+		n, err := strconv.Atoi(remaining[i])
+		if err != nil || n < 0 || n > checksToWin {
+			return fmt.Errorf("bad check count %q", field)
+		}
+		*cb.ChecksGiven(color) = checksToWin - n
+	}
+	return nil
+}
+
+// checksField renders the three-check field of a FEN
+func (cb *ChessBoard) checksField() string {
+	return fmt.Sprintf("%d+%d", checksToWin-*cb.ChecksGiven(true), checksToWin-*cb.ChecksGiven(false))
+}
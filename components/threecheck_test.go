@@ -0,0 +1,37 @@
+package components
+
+import "testing"
+
+func TestThreeCheckWinsOnThirdCheck(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		fen    string
+		move   string
+		checks string
+		want   Result
+	}{
+		{"first check", "4k3/8/8/8/8/8/8/R3K3 w - - 3+3 0 1", "a1a8", "2+3", Ongoing},
+		{"second check", "4k3/8/8/8/8/8/8/R3K3 w - - 2+3 0 1", "a1a8", "1+3", Ongoing},
+		{"third check", "4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1", "a1a8", "0+3", WhiteWins},
+		{"quiet move", "4k3/8/8/8/8/8/8/R3K3 w - - 1+3 0 1", "a1a2", "1+3", Ongoing},
+		{"black's third check", "r3k3/8/8/8/8/8/8/4K3 b - - 3+1 0 1", "a8a1", "3+0", BlackWins},
+	} {
+		board, err := ParseFEN(test.fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if board.Variant != threeCheckName {
+			t.Fatalf("%s: variant %q, want %q", test.name, board.Variant, threeCheckName)
+		}
+		child := playMove(t, ThreeCheck{}, &board, test.move)
+		if got := child.checksField(); got != test.checks {
+			t.Errorf("%s: checks left %s, want %s", test.name, got, test.checks)
+		}
+		if got := GameResult(ThreeCheck{}, child); got != test.want {
+			t.Errorf("%s: result %s, want %s", test.name, got, test.want)
+		}
+		if test.want != Ongoing && len(LegalMoves(ThreeCheck{}, child)) != 0 {
+			t.Errorf("%s: moves left after the game was won", test.name)
+		}
+	}
+}
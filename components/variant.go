@@ -0,0 +1,199 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Result is the outcome of a game
+type Result int
+
+const (
+	Ongoing Result = iota
+	WhiteWins
+	BlackWins
+	Draw
+)
+
+func (result Result) String() string {
+	switch result {
+	case WhiteWins:
+		return "1-0"
+	case BlackWins:
+		return "0-1"
+	case Draw:
+		return "1/2-1/2"
+	default:
+		return "*"
+	}
+}
+
+// ForSide returns 1 if the result is a win for the given color, -1 for a
+// loss and 0 for a draw or an ongoing game
+func (result Result) ForSide(color bool) int {
+	switch {
+	case result == WhiteWins && color, result == BlackWins && !color:
+		return 1
+	case result == WhiteWins, result == BlackWins:
+		return -1
+	}
+	return 0
+}
+
+// winFor returns the result of a game the given color has won
+func winFor(color bool) Result {
+	if color {
+		return WhiteWins
+	}
+	return BlackWins
+}
+
+// Variant is a rule set played on a ChessBoard. Moves are generated in two
+// steps, pseudo-legal moves followed by the legality predicate, so variants
+// can change either without repeating the other.
+type Variant interface {
+	// Name identifies the variant in stored positions and on the command
+	// line
+	Name() string
+
+	// StartPosition returns the board a game starts from
+	StartPosition() ChessBoard
+
+	// PseudoLegalMoves returns the children of board before the legality
+	// predicate is applied, with NextTurn handed to the opponent.
+	// capturesOnly restricts them to captures and promotions for quiescence
+	// search.
+	PseudoLegalMoves(board *ChessBoard, capturesOnly bool) []*ChessBoard
+
+	// IsLegal reports whether a position reached by a move may stand, in
+	// standard chess whether the side that just moved kept its king safe
+	IsLegal(board *ChessBoard) bool
+
+	// InCheck reports whether the side to move is in check
+	InCheck(board *ChessBoard) bool
+
+	// Decided returns the outcome of a game that is over whatever the side
+	// to move could play, and Ongoing otherwise. It is cheap enough to call
+	// before generating moves.
+	Decided(board *ChessBoard) Result
+
+	// Result returns the outcome of the game in board, given the legal moves
+	// of its side to move
+	Result(board *ChessBoard, moves []*ChessBoard) Result
+}
+
+// variants holds every rule set by name
+var variants = map[string]Variant{
+	standardName:      Standard{},
+	crazyhouseName:    Crazyhouse{},
+	atomicName:        Atomic{},
+	antichessName:     Antichess{},
+	threeCheckName:    ThreeCheck{},
+	kingOfTheHillName: KingOfTheHill{},
+	capablancaName:    Capablanca{},
+}
+
+// VariantByName returns the variant with the given name; the empty name is
+// standard chess
+func VariantByName(name string) (Variant, error) {
+	if name == "" {
+		return Standard{}, nil
+	}
+	variant, ok := variants[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variant %q (expected one of %v)", name, VariantNames())
+	}
+	return variant, nil
+}
+
+// VariantNames lists the names of all variants
+func VariantNames() []string {
+	names := make([]string, 0, len(variants))
+	for name := range variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// VariantOf returns the rules a board is played under, standard chess for
+// boards without a known variant
+func VariantOf(board *ChessBoard) Variant {
+	variant, err := VariantByName(board.Variant)
+	if err != nil {
+		return Standard{}
+	}
+	return variant
+}
+
+// LegalMoves returns the legal children of board under a variant's rules.
+// A game already decided has none.
+func LegalMoves(variant Variant, board *ChessBoard) []*ChessBoard {
+	return legalChildren(variant, board, false)
+}
+
+// LegalCaptures is LegalMoves restricted to captures and promotions
+func LegalCaptures(variant Variant, board *ChessBoard) []*ChessBoard {
+	return legalChildren(variant, board, true)
+}
+
+func legalChildren(variant Variant, board *ChessBoard, capturesOnly bool) []*ChessBoard {
+	if variant.Decided(board) != Ongoing {
+		return nil
+	}
+	children := variant.PseudoLegalMoves(board, capturesOnly)
+	legal := children[:0]
+	for _, child := range children {
+		if variant.IsLegal(child) {
+			legal = append(legal, child)
+		}
+	}
+	return legal
+}
+
+// GameResult returns the outcome of the game in board under a variant's rules
+func GameResult(variant Variant, board *ChessBoard) Result {
+	return variant.Result(board, LegalMoves(variant, board))
+}
+
+const standardName = "standard"
+
+// Standard is orthodox chess, including Chess960 boards
+type Standard struct{}
+
+func (Standard) Name() string {
+	return standardName
+}
+
+func (Standard) StartPosition() ChessBoard {
+	board, _ := ParseFEN(StartFEN)
+	return board
+}
+
+func (Standard) PseudoLegalMoves(board *ChessBoard, capturesOnly bool) []*ChessBoard {
+	return board.PseudoLegalMoves(capturesOnly)
+}
+
+func (Standard) IsLegal(board *ChessBoard) bool {
+	return board.IsLegalPosition()
+}
+
+func (Standard) InCheck(board *ChessBoard) bool {
+	return board.IsInCheck(board.NextTurn)
+}
+
+// Decided is always Ongoing, as only running out of moves ends the game
+func (Standard) Decided(board *ChessBoard) Result {
+	return Ongoing
+}
+
+// Result is a win for the side giving checkmate and a draw on stalemate
+func (Standard) Result(board *ChessBoard, moves []*ChessBoard) Result {
+	switch {
+	case len(moves) > 0:
+		return Ongoing
+	case board.IsInCheck(board.NextTurn):
+		return winFor(!board.NextTurn)
+	}
+	return Draw
+}
@@ -0,0 +1,61 @@
+package components
+
+import (
+	"sort"
+	"testing"
+)
+
+// parseVariantFEN parses fen as a position of the named variant
+func parseVariantFEN(t *testing.T, fen, variant string) *ChessBoard {
+	t.Helper()
+	board, err := ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.Variant = variant
+	return &board
+}
+
+// moveNames returns the UCI text of the moves leading to children, sorted
+func moveNames(children []*ChessBoard) []string {
+	names := make([]string, len(children))
+	for i, child := range children {
+		names[i] = child.LastMove.UCI()
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasMove reports whether a move in UCI text leads to one of children
+func hasMove(children []*ChessBoard, uci string) bool {
+	for _, child := range children {
+		if child.LastMove.UCI() == uci {
+			return true
+		}
+	}
+	return false
+}
+
+// playMove returns the child of board reached by a legal move in UCI text
+func playMove(t *testing.T, variant Variant, board *ChessBoard, uci string) *ChessBoard {
+	t.Helper()
+	for _, child := range LegalMoves(variant, board) {
+		if child.LastMove.UCI() == uci {
+			return child
+		}
+	}
+	t.Fatalf("%s is not legal in %s", uci, board.ToFEN())
+	return nil
+}
+
+// perft counts the leaf positions depth plies below board
+func perft(variant Variant, board *ChessBoard, depth int) int {
+	if depth == 0 {
+		return 1
+	}
+	nodes := 0
+	for _, child := range LegalMoves(variant, board) {
+		nodes += perft(variant, child, depth-1)
+	}
+	return nodes
+}
@@ -0,0 +1,171 @@
+package components
+
+// Zobrist keys are generated from a fixed seed so hashes are stable across
+// runs and can be stored alongside positions
+var (
+	zobristPieces     [2][6][8][8]uint64
+	zobristSideToMove uint64
+	zobristCastling   [4]uint64
+	zobristEnPassant  [8]uint64
+
+	// Crazyhouse keys, generated after the others so standard hashes are
+	// unchanged. A pocket can hold at most 16 pieces of one kind.
+	zobristPockets  [2][len(pocketLetters)][17]uint64
+	zobristPromoted [8][8]uint64
+
+	// Three-check keys for each side's count of checks given
+	zobristChecks [2][checksToWin + 1]uint64
+
+	// Capablanca keys for the compound pieces and for the squares and en
+	// passant files past the eighth file
+	zobristWidePieces    [2][8][Ranks][MaxFiles]uint64
+	zobristWideEnPassant [MaxFiles]uint64
+)
+
+// zobristPieceIndex maps a piece letter to its row in the key tables, and
+// letters without keys to -1. An array keeps the lookup off a map in the
+// inner loop of Hash.
+var zobristPieceIndex [256]int8
+
+// zobristPieceLetters lists the pieces with keys in table order
+const zobristPieceLetters = "PNBRQKAC"
+
+func init() {
+	for i := range zobristPieceIndex {
+		zobristPieceIndex[i] = -1
+	}
+	for i := 0; i < len(zobristPieceLetters); i++ {
+		zobristPieceIndex[zobristPieceLetters[i]] = int8(i)
+	}
+
+	seed := uint64(0x9E3779B97F4A7C15)
+	next := func() uint64 {
+		// splitmix64
+		seed += 0x9E3779B97F4A7C15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		return z ^ (z >> 31)
+	}
+
+	for color := 0; color < 2; color++ {
+		for piece := 0; piece < 6; piece++ {
+			for y := 0; y < 8; y++ {
+				for x := 0; x < 8; x++ {
+					zobristPieces[color][piece][y][x] = next()
+				}
+			}
+		}
+	}
+	zobristSideToMove = next()
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+	for i := range zobristEnPassant {
+		zobristEnPassant[i] = next()
+	}
+	for color := range zobristPockets {
+		for piece := range zobristPockets[color] {
+			for count := range zobristPockets[color][piece] {
+				zobristPockets[color][piece][count] = next()
+			}
+		}
+	}
+	for y := range zobristPromoted {
+		for x := range zobristPromoted[y] {
+			zobristPromoted[y][x] = next()
+		}
+	}
+	for color := range zobristChecks {
+		for count := range zobristChecks[color] {
+			zobristChecks[color][count] = next()
+		}
+	}
+	for color := range zobristWidePieces {
+		for piece := range zobristWidePieces[color] {
+			for y := range zobristWidePieces[color][piece] {
+				for x := range zobristWidePieces[color][piece][y] {
+					zobristWidePieces[color][piece][y][x] = next()
+				}
+			}
+		}
+	}
+	for i := range zobristWideEnPassant {
+		zobristWideEnPassant[i] = next()
+	}
+}
+
+// zobristPiece returns the key of a piece on a square, taken from the
+// standard table where it has one
+func zobristPiece(color, piece, y, x int) uint64 {
+	if piece < 6 && x < 8 {
+		return zobristPieces[color][piece][y][x]
+	}
+	return zobristWidePieces[color][piece][y][x]
+}
+
+// Hash returns the Zobrist hash of the position: piece placement, side to
+// move, castling rights, en passant file, crazyhouse pockets and promoted
+// pieces, and three-check counts. Score and LastMove are not part of the
+// position and are ignored. Variant, Chess960 and CastlingFiles are left
+// out too so stored hashes stay stable; a transposition table must be
+// cleared when they change.
+func (cb *ChessBoard) Hash() uint64 {
+	var hash uint64
+	for y, row := range cb.Board {
+		for x, piece := range row {
+			if piece == nil {
+				continue
+			}
+			color := 0
+			if !piece.GetColor() {
+				color = 1
+			}
+			index := int(zobristPieceIndex[pieceLetter(piece)])
+			if index < 0 {
+				// A piece type without keys would hash the same as a pawn
+				panic("no Zobrist keys for " + piece.ToString())
+			}
+			hash ^= zobristPiece(color, index, y, x)
+		}
+	}
+	if !cb.NextTurn {
+		hash ^= zobristSideToMove
+	}
+	for i, allowed := range []bool{
+		cb.Castling.WhiteKingside, cb.Castling.WhiteQueenside,
+		cb.Castling.BlackKingside, cb.Castling.BlackQueenside,
+	} {
+		if allowed {
+			hash ^= zobristCastling[i]
+		}
+	}
+	if cb.EnPassant != nil {
+		if cb.EnPassant.X < 8 {
+			hash ^= zobristEnPassant[cb.EnPassant.X]
+		} else {
+			hash ^= zobristWideEnPassant[cb.EnPassant.X]
+		}
+	}
+	for color, pocket := range cb.Pockets {
+		for piece, count := range pocket {
+			if count > 0 {
+				hash ^= zobristPockets[color][piece][min(count, 16)]
+			}
+		}
+	}
+	// Only crazyhouse, played on eight files, has promoted pieces
+	for y, row := range cb.Promoted {
+		for x, promoted := range row[:8] {
+			if promoted {
+				hash ^= zobristPromoted[y][x]
+			}
+		}
+	}
+	for color, checks := range cb.Checks {
+		if checks > 0 {
+			hash ^= zobristChecks[color][min(checks, checksToWin)]
+		}
+	}
+	return hash
+}
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
@@ -16,6 +15,8 @@ import (
 	"time"
 
 	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/search"
+	"github.com/LIAMBB/chess-compute/tablebase"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -24,61 +25,31 @@ type BoardRouteNode struct {
 	NextStateIDs []int
 }
 
-func checkDatabaseSize(db *sql.DB, maxSizeBytes int64) (bool, error) {
-	// Force a checkpoint to ensure WAL is considered
-	_, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)")
-	if err != nil {
-		return false, fmt.Errorf("checkpoint error: %v", err)
-	}
-
-	var size, walSize int64
-
-	// Get main DB size
-	err = db.QueryRow("SELECT page_count * page_size FROM pragma_page_count, pragma_page_size").Scan(&size)
+// checkStoreSize reports whether the store has grown past 90% of
+// maxSizeBytes, leaving a safety margin
+func checkStoreSize(store PositionStore, maxSizeBytes int64) (bool, error) {
+	size, err := store.Size()
 	if err != nil {
 		return false, err
 	}
 
-	// Get WAL size
-	err = db.QueryRow("SELECT SUM(s) FROM (SELECT size as s FROM pragma_wal_checkpoint)").Scan(&walSize)
-	if err != nil {
-		// If error, just use main DB size
-		walSize = 0
-	}
-
-	totalSize := size + walSize
-
-	// Use a safety margin (90% of max size)
 	margin := int64(float64(maxSizeBytes) * 0.9)
-	exceeded := totalSize >= margin
+	exceeded := size >= margin
 
 	if exceeded {
-		fmt.Printf("Database size check: Total %.2f GB (DB: %.2f GB, WAL: %.2f GB), Limit: %.2f GB\n",
-			float64(totalSize)/1024/1024/1024,
+		fmt.Printf("Store size check: Total %.2f GB, Limit: %.2f GB\n",
 			float64(size)/1024/1024/1024,
-			float64(walSize)/1024/1024/1024,
 			float64(maxSizeBytes)/1024/1024/1024)
 	}
 
 	return exceeded, nil
 }
 
-func simulateGamesWithSQLite(ctx context.Context, rootNode *BoardRouteNode, db *sql.DB, maxDepth int, maxSizeBytes int64) {
-	// Create a new context that can be cancelled when DB is full
+func simulateGames(ctx context.Context, rootNode *BoardRouteNode, store PositionStore, variant components.Variant, maxDepth int, maxSizeBytes int64) {
+	// Create a new context that can be cancelled when the store is full
 	dbCtx, dbCancel := context.WithCancel(ctx)
 	defer dbCancel() // Ensure cleanup
 
-	// Create excess queue table
-	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS excess_queue (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		state_id INTEGER NOT NULL,
-		depth INTEGER NOT NULL
-	)`)
-	if err != nil {
-		fmt.Println("Error creating excess queue table:", err)
-		return
-	}
-
 	queue := []*BoardRouteNode{rootNode}
 	currentDepth := 0
 	batchSize := 100
@@ -86,34 +57,36 @@ func simulateGamesWithSQLite(ctx context.Context, rootNode *BoardRouteNode, db *
 	totalProcessed := 0
 	statesAtDepth := make(map[int]int)
 
-	// Add transaction for storing node relations
-	tx, err := db.Begin()
-	if err != nil {
-		fmt.Println("Error starting transaction:", err)
-		return
-	}
+	// storeChildren stores a batch of boards and their edges from the parent
+	// nodes at the same index, labeled with the moves at the same index,
+	// returning the new nodes
+	storeChildren := func(boards []*components.ChessBoard, parents []*BoardRouteNode, labels []MoveLabel) ([]*BoardRouteNode, error) {
+		ids, err := store.PutPositions(boards)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store board states batch: %v", err)
+		}
 
-	stmt, err := tx.Prepare(`
-		INSERT OR IGNORE INTO node_relations (parent_id, child_id) 
-		VALUES (?, ?)
-	`)
-	if err != nil {
-		fmt.Println("Error preparing statement:", err)
-		tx.Rollback()
-		return
+		var nodes []*BoardRouteNode
+		for i, id := range ids {
+			parents[i].NextStateIDs = append(parents[i].NextStateIDs, id)
+			if err := store.AddEdge(parents[i].StateID, id, labels[i]); err != nil {
+				return nodes, err
+			}
+			nodes = append(nodes, &BoardRouteNode{StateID: id, NextStateIDs: []int{}})
+		}
+		return nodes, nil
 	}
-	defer stmt.Close()
 
 	for len(queue) > 0 && currentDepth < maxDepth {
-		// Check database size before processing new depth
-		exceeded, err := checkDatabaseSize(db, maxSizeBytes)
+		// Check store size before processing new depth
+		exceeded, err := checkStoreSize(store, maxSizeBytes)
 		if err != nil {
-			fmt.Printf("Error checking database size: %v\n", err)
+			fmt.Printf("Error checking store size: %v\n", err)
 			dbCancel() // Cancel context on error
 			return
 		} else if exceeded {
-			fmt.Println("Database size limit reached, stopping simulation")
-			dbCancel() // Cancel context when DB is full
+			fmt.Println("Store size limit reached, stopping simulation")
+			dbCancel() // Cancel context when the store is full
 			return
 		}
 
@@ -146,24 +119,16 @@ func simulateGamesWithSQLite(ctx context.Context, rootNode *BoardRouteNode, db *
 					defer logGoroutineExit(name)
 					defer wg.Done()
 
-					// Each worker gets its own connection to avoid WAL mode contention
-					workerDB, err := sql.Open("sqlite3", "./chess.db")
-					if err != nil {
-						fmt.Printf("Worker %d failed to open DB: %v\n", workerID, err)
-						return
-					}
-					defer workerDB.Close()
-
 					for work := range workChan {
-						// Check DB size at start of each work batch
-						exceeded, err := checkDatabaseSize(workerDB, maxSizeBytes)
+						// Check store size at start of each work batch
+						exceeded, err := checkStoreSize(store, maxSizeBytes)
 						if err != nil {
-							fmt.Printf("Worker %d error checking DB size: %v\n", workerID, err)
+							fmt.Printf("Worker %d error checking store size: %v\n", workerID, err)
 							dbCancel()
 							return
 						}
 						if exceeded {
-							fmt.Printf("Worker %d detected DB size limit reached\n", workerID)
+							fmt.Printf("Worker %d detected store size limit reached\n", workerID)
 							dbCancel()
 							return
 						}
@@ -175,98 +140,77 @@ func simulateGamesWithSQLite(ctx context.Context, rootNode *BoardRouteNode, db *
 							var localResults []*BoardRouteNode
 							var boardsToStore []*components.ChessBoard
 							var parentNodes []*BoardRouteNode // Track parent nodes for each board
+							var labels []MoveLabel            // and the move to each board
 							batchSize := 100                  // Adjust this value as needed
 
 							for j := work.start; j < work.end; j++ {
-								// Check DB size periodically during processing
+								// Check store size periodically during processing
 								if j%10 == 0 { // Check every 10 states
-									exceeded, err := checkDatabaseSize(workerDB, maxSizeBytes)
+									exceeded, err := checkStoreSize(store, maxSizeBytes)
 									if err != nil {
-										fmt.Printf("Worker %d error checking DB size: %v\n", workerID, err)
+										fmt.Printf("Worker %d error checking store size: %v\n", workerID, err)
 										dbCancel()
 										return
 									}
 									if exceeded {
-										fmt.Printf("Worker %d detected DB size limit reached\n", workerID)
+										fmt.Printf("Worker %d detected store size limit reached\n", workerID)
 										dbCancel()
 										return
 									}
 								}
 
 								node := queue[j]
-								currentBoard := getBoardStateByID(workerDB, node.StateID)
-								if currentBoard == nil {
-									errChan <- fmt.Errorf("nil board state for ID %d", node.StateID)
+								currentBoard, err := store.Position(node.StateID)
+								if err != nil {
+									errChan <- err
+									continue
+								}
+
+								// Tablebase positions are solved leaves and
+								// finished games have no moves to explore
+								if _, ok := tablebases.Probe(currentBoard); ok {
+									continue
+								}
+								if variant.Decided(currentBoard) != components.Ongoing {
 									continue
 								}
 
-								for y, row := range currentBoard.Board {
-									for x, piece := range row {
-										if piece != nil && piece.GetColor() == currentBoard.NextTurn {
-											possibleMoves := piece.GetPossibleMoves(*currentBoard, components.Coordinates{X: x, Y: y}, false)
-											for _, newBoard := range possibleMoves {
-												newBoard.NextTurn = !currentBoard.NextTurn
-												boardsToStore = append(boardsToStore, newBoard)
-												parentNodes = append(parentNodes, node)
-
-												// Process batch if we've reached the batch size
-												if len(boardsToStore) >= batchSize {
-													ids, err := storeBoardStatesBatch(workerDB, boardsToStore)
-													if err != nil {
-														errChan <- fmt.Errorf("failed to store board states batch: %v", err)
-														continue
-													}
-
-													// Create nodes and store relations for the batch
-													for i, id := range ids {
-														newNode := &BoardRouteNode{
-															StateID:      id,
-															NextStateIDs: []int{},
-														}
-														parentNodes[i].NextStateIDs = append(parentNodes[i].NextStateIDs, id)
-														if err := storeNodeRelation(workerDB, parentNodes[i].StateID, id); err != nil {
-															errChan <- fmt.Errorf("failed to store node relation: %v", err)
-															continue
-														}
-														localResults = append(localResults, newNode)
-													}
-
-													// Clear the batches
-													boardsToStore = boardsToStore[:0]
-													parentNodes = parentNodes[:0]
-												}
-											}
+								sans := currentBoard.SANs()
+								for _, newBoard := range variant.PseudoLegalMoves(currentBoard, false) {
+									boardsToStore = append(boardsToStore, newBoard)
+									parentNodes = append(parentNodes, node)
+									labels = append(labels, newMoveLabel(currentBoard, sans, newBoard.LastMove))
+
+									// Process batch if we've reached the batch size
+									if len(boardsToStore) >= batchSize {
+										nodes, err := storeChildren(boardsToStore, parentNodes, labels)
+										if err != nil {
+											errChan <- err
 										}
+										localResults = append(localResults, nodes...)
+
+										// Clear the batches
+										boardsToStore = boardsToStore[:0]
+										parentNodes = parentNodes[:0]
+										labels = labels[:0]
 									}
 								}
 
 								// Add size check inside worker
-								exceeded, err := checkDatabaseSize(workerDB, maxSizeBytes)
+								exceeded, err := checkStoreSize(store, maxSizeBytes)
 								if err != nil || exceeded {
-									dbCancel() // Cancel context if DB is full
+									dbCancel() // Cancel context if the store is full
 									return
 								}
 							}
 
 							// Process any remaining boards in the final batch
 							if len(boardsToStore) > 0 {
-								ids, err := storeBoardStatesBatch(workerDB, boardsToStore)
+								nodes, err := storeChildren(boardsToStore, parentNodes, labels)
 								if err != nil {
-									errChan <- fmt.Errorf("failed to store final board states batch: %v", err)
-								} else {
-									for i, id := range ids {
-										newNode := &BoardRouteNode{
-											StateID:      id,
-											NextStateIDs: []int{},
-										}
-										parentNodes[i].NextStateIDs = append(parentNodes[i].NextStateIDs, id)
-										if err := storeNodeRelation(workerDB, parentNodes[i].StateID, id); err != nil {
-											errChan <- fmt.Errorf("failed to store node relation: %v", err)
-											continue
-										}
-										localResults = append(localResults, newNode)
-									}
+									errChan <- err
 								}
+								localResults = append(localResults, nodes...)
 							}
 
 							select {
@@ -319,13 +263,13 @@ func simulateGamesWithSQLite(ctx context.Context, rootNode *BoardRouteNode, db *
 
 			statesAtDepth[currentDepth] = len(newQueue)
 
-			// Store excess states in database
+			// Move excess states to the store's frontier
 			if len(newQueue) > queueLimit {
-				fmt.Printf("Storing %d excess states in database at depth %d\n", len(newQueue)-queueLimit, currentDepth)
+				fmt.Printf("Storing %d excess states in the frontier at depth %d\n", len(newQueue)-queueLimit, currentDepth)
 				excessQueue := newQueue[queueLimit:]
 				newQueue = newQueue[:queueLimit]
 
-				// Store excess states in database in batches
+				// Store excess states in batches
 				batchSize := 1000
 				for i := 0; i < len(excessQueue); i += batchSize {
 					end := i + batchSize
@@ -333,172 +277,82 @@ func simulateGamesWithSQLite(ctx context.Context, rootNode *BoardRouteNode, db *
 						end = len(excessQueue)
 					}
 
-					tx, err := db.Begin()
-					if err != nil {
-						fmt.Println("Error starting transaction for excess queue:", err)
-						continue
-					}
-
-					stmt, err := tx.Prepare("INSERT INTO excess_queue (state_id, depth) VALUES (?, ?)")
-					if err != nil {
-						fmt.Println("Error preparing excess queue statement:", err)
-						tx.Rollback()
-						continue
-					}
-
+					ids := make([]int, 0, end-i)
 					for _, node := range excessQueue[i:end] {
-						_, err := stmt.Exec(node.StateID, currentDepth)
-						if err != nil {
-							fmt.Printf("Error storing excess state %d: %v\n", node.StateID, err)
-						}
+						ids = append(ids, node.StateID)
 					}
-
-					stmt.Close()
-					if err := tx.Commit(); err != nil {
-						fmt.Println("Error committing excess queue transaction:", err)
+					if err := store.Enqueue(currentDepth, ids); err != nil {
+						fmt.Println("Error storing excess states:", err)
 					}
 				}
 			}
 
 			// Process all stored states at current depth before moving on
 			for {
-				// Check database size before processing stored states
-				exceeded, err := checkDatabaseSize(db, maxSizeBytes)
+				// Check store size before each batch of stored states
+				exceeded, err := checkStoreSize(store, maxSizeBytes)
 				if err != nil {
-					fmt.Printf("Error checking database size: %v\n", err)
+					fmt.Printf("Error checking store size: %v\n", err)
 					dbCancel() // Cancel context on error
 					return
 				} else if exceeded {
-					fmt.Println("Database size limit reached while processing stored states, stopping simulation")
-					dbCancel() // Cancel context when DB is full
+					fmt.Println("Store size limit reached while processing stored states, stopping simulation")
+					dbCancel() // Cancel context when the store is full
+					return
+				}
+
+				select {
+				case <-dbCtx.Done():
+					fmt.Println("Processing cancelled, stopping excess queue processing...")
 					return
+				default:
 				}
 
-				// Count remaining states at current depth
-				var remainingCount int
-				err = db.QueryRow("SELECT COUNT(*) FROM excess_queue WHERE depth = ?", currentDepth).Scan(&remainingCount)
+				// Fetch the next batch of stored states
+				storedIDs, err := store.Dequeue(currentDepth, 1000)
 				if err != nil {
-					fmt.Println("Error counting remaining states:", err)
+					fmt.Println("Error reading the frontier:", err)
 					break
 				}
-
-				if remainingCount == 0 {
+				if len(storedIDs) == 0 {
 					break
 				}
+				fmt.Printf("Processing %d stored states at depth %d\n", len(storedIDs), currentDepth)
 
-				fmt.Printf("Processing %d remaining states at depth %d\n", remainingCount, currentDepth)
-
-				// Process stored states in smaller batches
-				batchSize := 1000
-				for i := 0; i < remainingCount; i += batchSize {
-					select {
-					case <-dbCtx.Done():
-						fmt.Println("Processing cancelled, stopping excess queue processing...")
-						return
-					default:
-						// Check size before each batch
-						exceeded, err := checkDatabaseSize(db, maxSizeBytes)
-						if err != nil {
-							fmt.Printf("Error checking database size: %v\n", err)
-							dbCancel()
-							return
-						} else if exceeded {
-							fmt.Println("Database size limit reached during batch processing, stopping simulation")
-							dbCancel()
-							return
-						}
-
-						// Fetch next batch of stored states
-						rows, err := db.Query("SELECT state_id FROM excess_queue WHERE depth = ? LIMIT ? OFFSET ?",
-							currentDepth, batchSize, i)
-						if err != nil {
-							fmt.Println("Error querying excess queue:", err)
-							break
-						}
-
-						var storedStates []*BoardRouteNode
-						var processedIDs []int
-						for rows.Next() {
-							var stateID int
-							err := rows.Scan(&stateID)
-							if err != nil {
-								fmt.Println("Error scanning excess queue row:", err)
-								continue
-							}
-							storedStates = append(storedStates, &BoardRouteNode{StateID: stateID, NextStateIDs: []int{}})
-							processedIDs = append(processedIDs, stateID)
-						}
-						rows.Close()
-
-						if len(storedStates) == 0 {
-							break
-						}
-
-						// Delete processed states
-						if len(processedIDs) > 0 {
-							query := "DELETE FROM excess_queue WHERE depth = ? AND state_id IN ("
-							params := []interface{}{currentDepth}
-							for i, id := range processedIDs {
-								if i > 0 {
-									query += ","
-								}
-								query += "?"
-								params = append(params, id)
-							}
-							query += ")"
-
-							_, err = db.Exec(query, params...)
-							if err != nil {
-								fmt.Println("Error deleting processed excess states:", err)
-							}
-						}
-
-						// Process stored states and add results to newQueue instead of replacing queue
-						tempQueue := storedStates
-						var tempResults []*BoardRouteNode
-
-						// Process the stored states similar to main loop
-						for i := 0; i < len(tempQueue); i += batchSize {
-							end := i + batchSize
-							if end > len(tempQueue) {
-								end = len(tempQueue)
-							}
-
-							for j := i; j < end; j++ {
-								node := tempQueue[j]
-								currentBoard := getBoardStateByID(db, node.StateID)
-								if currentBoard == nil {
-									continue
-								}
-
-								for y, row := range currentBoard.Board {
-									for x, piece := range row {
-										if piece != nil && piece.GetColor() == currentBoard.NextTurn {
-											possibleMoves := piece.GetPossibleMoves(*currentBoard, components.Coordinates{X: x, Y: y}, false)
-											for _, newBoard := range possibleMoves {
-												newBoard.NextTurn = !currentBoard.NextTurn
-												newStateID := storeBoardState(db, newBoard)
-												newNode := &BoardRouteNode{
-													StateID:      newStateID,
-													NextStateIDs: []int{},
-												}
-												if err := storeNodeRelation(db, node.StateID, newStateID); err != nil {
-													fmt.Printf("Error storing node relation: %v\n", err)
-													continue
-												}
-												tempResults = append(tempResults, newNode)
-											}
-										}
-									}
-								}
-							}
-						}
+				// Process the stored states similar to main loop, adding
+				// the results to newQueue
+				var tempResults []*BoardRouteNode
+				for _, stateID := range storedIDs {
+					currentBoard, err := store.Position(stateID)
+					if err != nil {
+						fmt.Println("Error reading stored state:", err)
+						continue
+					}
+					if _, ok := tablebases.Probe(currentBoard); ok {
+						continue
+					}
+					if variant.Decided(currentBoard) != components.Ongoing {
+						continue
+					}
 
-						// Add processed stored states to newQueue
-						newQueue = append(newQueue, tempResults...)
-						totalProcessed += len(tempQueue)
+					children := variant.PseudoLegalMoves(currentBoard, false)
+					parents := make([]*BoardRouteNode, len(children))
+					labels := make([]MoveLabel, len(children))
+					node := &BoardRouteNode{StateID: stateID, NextStateIDs: []int{}}
+					sans := currentBoard.SANs()
+					for i, child := range children {
+						parents[i] = node
+						labels[i] = newMoveLabel(currentBoard, sans, child.LastMove)
+					}
+					nodes, err := storeChildren(children, parents, labels)
+					if err != nil {
+						fmt.Printf("Error storing children of %d: %v\n", stateID, err)
 					}
+					tempResults = append(tempResults, nodes...)
 				}
+
+				newQueue = append(newQueue, tempResults...)
+				totalProcessed += len(storedIDs)
 			}
 
 			fmt.Printf("Depth %d complete. Generated %d new states. Total processed: %d\n",
@@ -506,9 +360,7 @@ func simulateGamesWithSQLite(ctx context.Context, rootNode *BoardRouteNode, db *
 			fmt.Printf("States at each depth: %v\n", statesAtDepth)
 
 			// Verify no states were left behind
-			var remainingCount int
-			err = db.QueryRow("SELECT COUNT(*) FROM excess_queue WHERE depth = ?", currentDepth).Scan(&remainingCount)
-			if err != nil {
+			if remainingCount, err := store.Pending(currentDepth); err != nil {
 				fmt.Println("Error checking for remaining states:", err)
 			} else if remainingCount > 0 {
 				fmt.Printf("Warning: %d states were left unprocessed at depth %d\n", remainingCount, currentDepth)
@@ -520,97 +372,6 @@ func simulateGamesWithSQLite(ctx context.Context, rootNode *BoardRouteNode, db *
 	}
 }
 
-func storeBoardState(db *sql.DB, board *components.ChessBoard) int {
-	data, err := json.Marshal(board)
-	if err != nil {
-		fmt.Println("Error marshaling state:", err)
-		return -1
-	}
-
-	result, err := db.Exec("INSERT INTO board_states (state) VALUES (?)", data)
-	if err != nil {
-		fmt.Println("Error inserting state into database:", err)
-		return -1
-	}
-
-	id, err := result.LastInsertId()
-	if err != nil {
-		fmt.Println("Error getting last insert ID:", err)
-		return -1
-	}
-
-	return int(id)
-}
-
-func getBoardStateByID(db *sql.DB, id int) *components.ChessBoard {
-	var data string
-	err := db.QueryRow("SELECT state FROM board_states WHERE id = ?", id).Scan(&data)
-	if err != nil {
-		fmt.Println("Error querying state from database:", err)
-		return nil
-	}
-
-	var board components.ChessBoard
-	err = json.Unmarshal([]byte(data), &board)
-	if err != nil {
-		fmt.Println("Error unmarshaling state:", err)
-		return nil
-	}
-
-	return &board
-}
-
-func storeBoardStatesBatch(db *sql.DB, boards []*components.ChessBoard) ([]int, error) {
-	tx, err := db.Begin()
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %v", err)
-	}
-	defer tx.Rollback() // Will be ignored if transaction is committed
-
-	// Prepare the insert statement
-	stmt, err := tx.Prepare("INSERT INTO board_states (state) VALUES (?)")
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare statement: %v", err)
-	}
-	defer stmt.Close()
-
-	var ids []int
-	for _, board := range boards {
-		data, err := json.Marshal(board)
-		if err != nil {
-			continue
-		}
-
-		// Try to find existing state first
-		var existingID int
-		err = tx.QueryRow("SELECT id FROM board_states WHERE state = ?", string(data)).Scan(&existingID)
-		if err == nil {
-			// State already exists
-			ids = append(ids, existingID)
-			continue
-		}
-
-		// State doesn't exist, insert it
-		result, err := stmt.Exec(string(data))
-		if err != nil {
-			continue
-		}
-
-		id, err := result.LastInsertId()
-		if err != nil {
-			continue
-		}
-
-		ids = append(ids, int(id))
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %v", err)
-	}
-
-	return ids, nil
-}
-
 func getDiskSpace() (uint64, uint64, error) {
 	var stat syscall.Statfs_t
 	err := syscall.Statfs("/", &stat)
@@ -646,6 +407,15 @@ func logGoroutineExit(name string) {
 }
 
 func main() {
+	// Subcommands work on an existing chess.db instead of exploring
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Get and display available disk space
 	available, total, err := getDiskSpace()
 	if err != nil {
@@ -667,6 +437,39 @@ func main() {
 		maxSizeGB = 10
 	}
 
+	// Exploration plays by the rules of one variant
+	fmt.Printf("Enter a variant %v, or press Enter for standard chess: ", components.VariantNames())
+	input, _ = reader.ReadString('\n')
+	variant, err := components.VariantByName(strings.TrimSpace(input))
+	if err != nil {
+		fmt.Println("Unknown variant, using standard chess")
+		variant = components.Standard{}
+	}
+	startingBoard := variant.StartPosition()
+
+	// Standard chess starts from the usual position or any Chess960 one
+	if _, ok := variant.(components.Standard); ok {
+		fmt.Print("Enter a Chess960 start position (0-959), or press Enter for standard chess: ")
+		input, _ = reader.ReadString('\n')
+		if input = strings.TrimSpace(input); input != "" {
+			index, err := strconv.Atoi(input)
+			if err == nil {
+				startingBoard, err = components.Chess960Position(index)
+			}
+			if err != nil {
+				fmt.Println("Invalid Chess960 position, using standard chess")
+				startingBoard = initGame()
+			} else {
+				fmt.Println("Starting from", startingBoard.ToFEN())
+			}
+		}
+	}
+
+	// Small runs can keep the tree in memory and leave chess.db alone
+	fmt.Print("Enter 'memory' to explore without saving to chess.db, or press Enter to use chess.db: ")
+	input, _ = reader.ReadString('\n')
+	inMemory := strings.TrimSpace(input) == "memory"
+
 	// Convert GB to bytes for SQLite
 	maxPages := int64(maxSizeGB * 1024 * 1024 * 1024 / 4096) // 4KB per page
 
@@ -684,67 +487,60 @@ func main() {
 		}
 	}()
 
-	db, err := sql.Open("sqlite3", "./chess.db")
-	if err != nil {
-		fmt.Println("Error opening database:", err)
-		return
-	}
-	defer db.Close()
-
-	// Enable WAL mode and optimize SQLite settings with size limit
-	_, err = db.Exec(fmt.Sprintf(`
-		PRAGMA journal_mode=WAL;
-		PRAGMA synchronous=NORMAL;
-		PRAGMA cache_size=10000;
-		PRAGMA temp_store=MEMORY;
-		PRAGMA mmap_size=30000000000;
-		PRAGMA max_page_count=%d;
-	`, maxPages))
-	if err != nil {
-		fmt.Println("Error setting SQLite pragmas:", err)
-		return
+	var store PositionStore
+	if inMemory {
+		store = NewMemoryStore()
+	} else {
+		db, err := sql.Open("sqlite3", "./chess.db")
+		if err != nil {
+			fmt.Println("Error opening database:", err)
+			return
+		}
+		defer db.Close()
+
+		// Enable WAL mode and optimize SQLite settings with size limit
+		_, err = db.Exec(fmt.Sprintf(`
+			PRAGMA journal_mode=WAL;
+			PRAGMA synchronous=NORMAL;
+			PRAGMA cache_size=10000;
+			PRAGMA temp_store=MEMORY;
+			PRAGMA mmap_size=30000000000;
+			PRAGMA max_page_count=%d;
+		`, maxPages))
+		if err != nil {
+			fmt.Println("Error setting SQLite pragmas:", err)
+			return
+		}
+
+		if err := createSchema(db); err != nil {
+			fmt.Println("Error creating table:", err)
+			return
+		}
+		store = NewSQLiteStore(db)
 	}
 
-	// Add function to periodically check database size
+	// Add function to periodically check store size
 	go func() {
-		logGoroutine("DB-Size-Monitor")
+		logGoroutine("Store-Size-Monitor")
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
 
 		for range ticker.C {
-			var size int64
-			err := db.QueryRow("SELECT page_count * page_size FROM pragma_page_count, pragma_page_size").Scan(&size)
+			size, err := store.Size()
 			if err != nil {
-				fmt.Printf("Error getting DB size: %v\n", err)
+				fmt.Printf("Error getting store size: %v\n", err)
 				continue
 			}
-			fmt.Printf("Current database size: %.2f GB\n", float64(size)/1024/1024/1024)
+			fmt.Printf("Current store size: %.2f GB\n", float64(size)/1024/1024/1024)
 		}
 	}()
 
-	_, err = db.Exec(`
-		CREATE TABLE IF NOT EXISTS board_states (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			state TEXT NOT NULL
-		);
-		CREATE TABLE IF NOT EXISTS node_relations (
-			parent_id INTEGER,
-			child_id INTEGER,
-			FOREIGN KEY(parent_id) REFERENCES board_states(id),
-			FOREIGN KEY(child_id) REFERENCES board_states(id),
-			PRIMARY KEY(parent_id, child_id)
-		);
-		CREATE INDEX IF NOT EXISTS idx_node_relations_parent 
-		ON node_relations(parent_id);
-	`)
+	rootIDs, err := store.PutPositions([]*components.ChessBoard{&startingBoard})
 	if err != nil {
-		fmt.Println("Error creating table:", err)
+		fmt.Println("Error storing the starting position:", err)
 		return
 	}
-
-	startingBoard := initGame()
-	rootStateID := storeBoardState(db, &startingBoard)
-	rootNode := &BoardRouteNode{StateID: rootStateID, NextStateIDs: []int{}}
+	rootNode := &BoardRouteNode{StateID: rootIDs[0], NextStateIDs: []int{}}
 	maxDepth := 7 // Set your desired maximum depth here
 
 	// Set up context and signal handling
@@ -784,7 +580,7 @@ func main() {
 	// Run simulation in a separate goroutine
 	go func() {
 		logGoroutine("Main-Simulation")
-		simulateGamesWithSQLite(ctx, rootNode, db, maxDepth, maxSizeBytes)
+		simulateGames(ctx, rootNode, store, variant, maxDepth, maxSizeBytes)
 		done <- true
 		computationDone <- true
 	}()
@@ -796,85 +592,178 @@ func main() {
 	case <-done:
 		fmt.Println("Simulation complete")
 		// Start CLI only if simulation completed normally
-		traverseTree(rootNode, db)
+		traverseTree(rootNode, store, variant)
 	}
 }
 
-func initGame() components.ChessBoard {
-	board := [8][8]components.ChessPiece{
-		{components.Rook{Color: true}, components.Knight{Color: true}, components.Bishop{Color: true}, components.Queen{Color: true}, components.King{Color: true}, components.Bishop{Color: true}, components.Knight{Color: true}, components.Rook{Color: true}},
-		{components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}},
-		{nil, nil, nil, nil, nil, nil, nil, nil},
-		{nil, nil, nil, nil, nil, nil, nil, nil},
-		{nil, nil, nil, nil, nil, nil, nil, nil},
-		{nil, nil, nil, nil, nil, nil, nil, nil},
-		{components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}},
-		{components.Rook{Color: false}, components.Knight{Color: false}, components.Bishop{Color: false}, components.Queen{Color: false}, components.King{Color: false}, components.Bishop{Color: false}, components.Knight{Color: false}, components.Rook{Color: false}},
+func createSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS board_states (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			state TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS node_relations (
+			parent_id INTEGER,
+			child_id INTEGER,
+			FOREIGN KEY(parent_id) REFERENCES board_states(id),
+			FOREIGN KEY(child_id) REFERENCES board_states(id),
+			PRIMARY KEY(parent_id, child_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_node_relations_parent 
+		ON node_relations(parent_id);
+		CREATE INDEX IF NOT EXISTS idx_node_relations_child
+		ON node_relations(child_id);
+		CREATE TABLE IF NOT EXISTS excess_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			state_id INTEGER NOT NULL,
+			depth INTEGER NOT NULL
+		);
+	`)
+	if err != nil {
+		return err
 	}
 
-	return components.ChessBoard{
-		Score:    0,
-		NextTurn: true, // Start with white's turn
-		Board:    board,
+	// Columns added after the original schema are migrated in place
+	if err := ensureColumn(db, "board_states", "minimax_value", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "board_states", "best_child_id", "INTEGER"); err != nil {
+		return err
 	}
-}
 
-func storeNodeRelation(db *sql.DB, parentID, childID int) error {
-	// Use INSERT OR IGNORE to handle potential duplicates
-	_, err := db.Exec(`
-		INSERT OR IGNORE INTO node_relations (parent_id, child_id) 
-		VALUES (?, ?)`,
-		parentID, childID)
+	// Monte Carlo Tree Search statistics per edge, see mcts.go
+	if err := ensureColumn(db, "node_relations", "visits", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "node_relations", "value_sum", "REAL NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "node_relations", "prior", "REAL"); err != nil {
+		return err
+	}
+
+	// The move each edge stands for, see MoveLabel. Edges stored before
+	// these columns existed are labeled by the label command.
+	for _, column := range []string{"move_from TEXT", "move_to TEXT", "move_promotion TEXT", "move_flags INTEGER", "move_san TEXT", "move_uci TEXT"} {
+		name, definition, _ := strings.Cut(column, " ")
+		if err := ensureColumn(db, "node_relations", name, definition); err != nil {
+			return err
+		}
+	}
+
+	// Outcome of the last proof-number search through a node, see proof.go
+	if err := ensureColumn(db, "board_states", "proof_status", "TEXT"); err != nil {
+		return err
+	}
+
+	// States are deduplicated by position hash, see findOrStoreState
+	if err := ensureColumn(db, "board_states", "state_hash", "INTEGER"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "board_states", "hash_slot", "INTEGER"); err != nil {
+		return err
+	}
+	_, err = db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_board_states_hash ON board_states(state_hash, hash_slot)")
 	if err != nil {
-		return fmt.Errorf("failed to store node relation: %v", err)
+		return fmt.Errorf("error creating state hash index: %v", err)
 	}
-	return nil
+	return backfillStateHashes(db)
 }
 
-func getChildNodes(db *sql.DB, parentID int) ([]int, error) {
-	rows, err := db.Query(`
-		SELECT child_id 
-		FROM node_relations 
-		WHERE parent_id = ?
-		ORDER BY child_id
-	`, parentID)
+// ensureColumn adds a column to an existing table if it is not there yet
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
 	if err != nil {
-		return nil, fmt.Errorf("error querying child nodes: %v", err)
+		return fmt.Errorf("error reading columns of %s: %v", table, err)
 	}
 	defer rows.Close()
 
-	var childIDs []int
 	for rows.Next() {
-		var childID int
-		if err := rows.Scan(&childID); err != nil {
-			return nil, fmt.Errorf("error scanning child ID: %v", err)
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("error scanning column name: %v", err)
 		}
-		childIDs = append(childIDs, childID)
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating over columns: %v", err)
 	}
+	rows.Close()
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("error adding column %s.%s: %v", table, column, err)
+	}
+	return nil
+}
+
+func initGame() components.ChessBoard {
+	board := [components.Ranks][components.MaxFiles]components.ChessPiece{
+		{components.Rook{Color: true}, components.Knight{Color: true}, components.Bishop{Color: true}, components.Queen{Color: true}, components.King{Color: true}, components.Bishop{Color: true}, components.Knight{Color: true}, components.Rook{Color: true}},
+		{components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}, components.Pawn{Color: true}},
+		{nil, nil, nil, nil, nil, nil, nil, nil},
+		{nil, nil, nil, nil, nil, nil, nil, nil},
+		{nil, nil, nil, nil, nil, nil, nil, nil},
+		{nil, nil, nil, nil, nil, nil, nil, nil},
+		{components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}, components.Pawn{Color: false}},
+		{components.Rook{Color: false}, components.Knight{Color: false}, components.Bishop{Color: false}, components.Queen{Color: false}, components.King{Color: false}, components.Bishop{Color: false}, components.Knight{Color: false}, components.Rook{Color: false}},
 	}
 
-	return childIDs, nil
+	return components.ChessBoard{
+		Score:    0,
+		NextTurn: true, // Start with white's turn
+		Board:    board,
+		Castling: components.CastlingRights{
+			WhiteKingside:  true,
+			WhiteQueenside: true,
+			BlackKingside:  true,
+			BlackQueenside: true,
+		},
+	}
 }
 
-func traverseTree(node *BoardRouteNode, db *sql.DB) {
+func traverseTree(node *BoardRouteNode, store PositionStore, variant components.Variant) {
 	reader := bufio.NewReader(os.Stdin)
 	currentNodeID := node.StateID
 	var history []int
 
+	// Multi-PV results for the current node, keyed by child ID
+	var analysis map[int]rankedLine
+
+	// Side whose fog-of-war view is shown, nil to show the whole board
+	var fog *bool
+
 	for {
 		fmt.Println("\nCurrent Board State (ID:", currentNodeID, "):")
-		board := getBoardStateByID(db, currentNodeID)
-		if board == nil {
-			fmt.Println("Error: Could not retrieve board state")
+		board, err := store.Position(currentNodeID)
+		if err != nil {
+			fmt.Println("Error: Could not retrieve board state:", err)
 			break
 		}
-		board.ToString()
+		if fog != nil {
+			view := board.FogView(*fog)
+			view.ToString()
+			fmt.Println("Fog:", view.FEN())
+		} else {
+			board.ToString()
+		}
+		if result := components.GameResult(variant, board); result != components.Ongoing {
+			fmt.Println("Game over:", result)
+		}
+		if v, ok := tablebases.Probe(board); ok {
+			fmt.Println("TB:", tablebase.Describe(v, board.NextTurn))
+		}
+		meta, err := store.Metadata(currentNodeID)
+		if err != nil {
+			fmt.Printf("Error getting node metadata: %v\n", err)
+		} else if meta.ProofStatus != nil {
+			fmt.Println("Proof:", *meta.ProofStatus)
+		}
 
-		// Get child nodes from database
-		childIDs, err := getChildNodes(db, currentNodeID)
+		// Get child nodes from the store
+		childIDs, err := store.Children(currentNodeID)
 		if err != nil {
 			fmt.Printf("Error getting child nodes: %v\n", err)
 			break
@@ -889,30 +778,83 @@ func traverseTree(node *BoardRouteNode, db *sql.DB) {
 				break
 			}
 		} else {
+			edges, err := store.EdgeStats(currentNodeID)
+			if err != nil {
+				fmt.Printf("Error getting MCTS statistics: %v\n", err)
+			}
+			moves, err := store.Moves(currentNodeID)
+			if err != nil {
+				fmt.Printf("Error getting moves: %v\n", err)
+			}
+			edgeStats := make(map[int]EdgeStat)
+			for _, edge := range edges {
+				edgeStats[edge.ChildID] = edge
+			}
+
 			for i, childID := range childIDs {
-				childBoard := getBoardStateByID(db, childID)
-				if childBoard != nil {
-					fmt.Printf("%d: Move to state ID %d (%s to move)\n",
-						i, childID,
-						map[bool]string{true: "White", false: "Black"}[childBoard.NextTurn])
+				if childBoard, err := store.Position(childID); err == nil {
+					if move, ok := moves[childID]; ok {
+						fmt.Printf("%d: %s (state ID %d)", i, move, childID)
+					} else {
+						fmt.Printf("%d: Move to state ID %d (%s to move)",
+							i, childID,
+							map[bool]string{true: "White", false: "Black"}[childBoard.NextTurn])
+					}
+					if edge := edgeStats[childID]; edge.Visits > 0 {
+						fmt.Printf("  %d visits, value %+.3f", edge.Visits, edge.MeanValue())
+					}
+					if line, ok := analysis[childID]; ok {
+						fmt.Printf("  #%d %s %s", line.Rank, components.FormatScore(line.Score), formatPV(line.PV))
+					}
+					fmt.Println()
+				}
+			}
+
+			// Show the result of the last minimax back-propagation, if any
+			if meta.BestChild != nil && meta.MinimaxValue != nil {
+				for i, childID := range childIDs {
+					if childID == *meta.BestChild {
+						fmt.Printf("Best: child %d (%s)\n", i, components.FormatScore(*meta.MinimaxValue))
+					}
 				}
 			}
 		}
 
-		fmt.Print("\nEnter move number, 'b' to go back, or 'q' to quit: ")
+		fmt.Print("\nEnter move number, 'b' to go back, 'm [depth]' to rank the moves, 'f [w|b]' to toggle fog of war, or 'q' to quit: ")
 		input, _ := reader.ReadString('\n')
 		input = strings.TrimSpace(input)
+		fields := strings.Fields(input)
 
-		switch input {
-		case "b":
+		switch {
+		case input == "b":
 			if len(history) > 0 {
 				currentNodeID = history[len(history)-1]
 				history = history[:len(history)-1]
+				analysis = nil
 			} else {
 				fmt.Println("Cannot go back further")
 			}
-		case "q":
+		case input == "q":
 			return
+		case len(fields) > 0 && fields[0] == "m":
+			depth := browseAnalysisDepth
+			if len(fields) > 1 {
+				if depth, err = strconv.Atoi(fields[1]); err != nil || depth < 1 {
+					fmt.Println("Invalid depth.")
+					continue
+				}
+			}
+			analysis = analyzeChildren(store, variant, *board, currentNodeID, depth)
+		case len(fields) > 0 && fields[0] == "f":
+			switch {
+			case len(fields) == 1:
+				fog = nil
+			case fields[1] == "w" || fields[1] == "b":
+				color := fields[1] == "w"
+				fog = &color
+			default:
+				fmt.Println("Invalid side, expected w or b.")
+			}
 		default:
 			moveIndex, err := strconv.Atoi(input)
 			if err != nil || moveIndex < 0 || moveIndex >= len(childIDs) {
@@ -921,30 +863,44 @@ func traverseTree(node *BoardRouteNode, db *sql.DB) {
 			}
 			history = append(history, currentNodeID)
 			currentNodeID = childIDs[moveIndex]
+			analysis = nil
 		}
 	}
 }
 
-// Add a helper function to debug the node relations
-func debugNodeRelations(db *sql.DB, nodeID int) {
-	rows, err := db.Query(`
-		SELECT parent_id, child_id 
-		FROM node_relations 
-		WHERE parent_id = ? OR child_id = ?
-	`, nodeID, nodeID)
+// Default depth of the browser's Multi-PV analysis
+const browseAnalysisDepth = 4
+
+// rankedLine is a Multi-PV line annotated onto a child in the browser
+type rankedLine struct {
+	Rank int
+	search.Line
+}
+
+// analyzeChildren runs a Multi-PV search over all moves of board, stored as
+// parentID, and matches the lines to the children through the moves on their
+// edges. Scores are from the side to move in board.
+func analyzeChildren(store PositionStore, variant components.Variant, board components.ChessBoard, parentID, depth int) map[int]rankedLine {
+	moves, err := store.Moves(parentID)
 	if err != nil {
-		fmt.Printf("Error querying relations: %v\n", err)
-		return
+		fmt.Printf("Error getting moves: %v\n", err)
+		return nil
+	}
+	childByMove := make(map[components.Move]int)
+	for childID, label := range moves {
+		childByMove[label.Move] = childID
 	}
-	defer rows.Close()
 
-	fmt.Printf("\nRelations for node %d:\n", nodeID)
-	for rows.Next() {
-		var parentID, childID int
-		if err := rows.Scan(&parentID, &childID); err != nil {
-			fmt.Printf("Error scanning row: %v\n", err)
-			continue
+	engine := search.NewEngine()
+	engine.Variant = variant
+	engine.Tablebases = tablebases
+	result := engine.Search(context.Background(), board, search.Limits{Depth: depth, MultiPV: len(components.LegalMoves(variant, &board))})
+
+	analysis := make(map[int]rankedLine)
+	for i, line := range result.Lines {
+		if childID, ok := childByMove[line.PV[0]]; ok {
+			analysis[childID] = rankedLine{Rank: i + 1, Line: line}
 		}
-		fmt.Printf("Parent: %d -> Child: %d\n", parentID, childID)
 	}
+	return analysis
 }
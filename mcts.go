@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/search"
+)
+
+const (
+	// Exploration constants of the two selection formulas
+	uctExploration  = 1.4
+	puctExploration = 1.5
+
+	// UCT scores an unvisited move this plus its prior, more than any
+	// visited move can score yet small enough for the prior to count
+	unvisitedBonus = 1e6
+
+	// Random playouts stop after this many plies and score the position
+	maxPlayoutPlies = 40
+
+	// Centipawn scale of the evaluation to value and prior conversions
+	valueScale       = 400.0
+	priorTemperature = 100.0
+
+	// Selection gives up on paths longer than this, which only happen when
+	// the stored graph contains cycles
+	maxSelectionDepth = 256
+)
+
+// runMCTS runs or resumes Monte Carlo Tree Search from a stored position:
+// mcts [nodeID] [iterations] [uct|puct] [random|eval]. Statistics are kept on
+// node_relations, so a search stopped with Ctrl-C continues where it left off
+// when run again.
+func runMCTS(db *sql.DB, args []string) error {
+	rootID, err := rootIDFromArgs(db, args)
+	if err != nil {
+		return err
+	}
+	iterations := 1000
+	if len(args) > 1 {
+		if iterations, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("invalid iteration count %q", args[1])
+		}
+	}
+	policy := "puct"
+	if len(args) > 2 {
+		policy = args[2]
+	}
+	playout := "eval"
+	if len(args) > 3 {
+		playout = args[3]
+	}
+	if policy != "uct" && policy != "puct" {
+		return fmt.Errorf("unknown selection policy %q (expected uct or puct)", policy)
+	}
+	if playout != "random" && playout != "eval" {
+		return fmt.Errorf("unknown playout %q (expected random or eval)", playout)
+	}
+
+	store := NewSQLiteStore(db)
+	root, err := store.Position(rootID)
+	if err != nil {
+		return err
+	}
+	variant := components.VariantOf(root)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	start := time.Now()
+	done := 0
+	for ; done < iterations && ctx.Err() == nil; done++ {
+		if err := mctsIteration(store, variant, rootID, policy, playout, rng); err != nil {
+			return err
+		}
+		if (done+1)%100 == 0 {
+			fmt.Printf("%d iterations (%s)\n", done+1, time.Since(start).Round(time.Millisecond))
+		}
+	}
+	fmt.Printf("Ran %d iterations in %s\n", done, time.Since(start).Round(time.Millisecond))
+
+	return printMCTSRoot(store, rootID)
+}
+
+// mctsIteration selects a path from rootID down to a leaf, expands the leaf,
+// scores it with a playout and backs the value up the path
+func mctsIteration(store PositionStore, variant components.Variant, rootID int, policy, playout string, rng *rand.Rand) error {
+	path := []int{rootID}
+	onPath := map[int]bool{rootID: true}
+	var value float64
+	repeated := false
+
+	for {
+		nodeID := path[len(path)-1]
+		edges, err := store.EdgeStats(nodeID)
+		if err != nil {
+			return err
+		}
+		if len(edges) == 0 || len(path) > maxSelectionDepth {
+			break
+		}
+		childID := edges[selectEdge(edges, policy)].ChildID
+		path = append(path, childID)
+		if onPath[childID] {
+			// A repeated position is scored as a draw
+			repeated = true
+			break
+		}
+		onPath[childID] = true
+	}
+
+	leafID := path[len(path)-1]
+	if !repeated {
+		board, err := store.Position(leafID)
+		if err != nil {
+			return err
+		}
+		if result := components.GameResult(variant, board); result != components.Ongoing {
+			value = float64(result.ForSide(board.NextTurn))
+		} else if v, ok := tablebases.Probe(board); ok {
+			// Tablebase positions are scored exactly and never expanded
+			value = float64(v.WDL())
+		} else {
+			if err := expandNode(store, variant, leafID, board); err != nil {
+				return err
+			}
+			value = runPlayout(variant, *board, playout, rng)
+		}
+	}
+
+	// value is from the side to move at the leaf; each edge stores it for
+	// the side to move in its parent
+	for i := len(path) - 1; i > 0; i-- {
+		value = -value
+		if err := store.AddVisit(path[i-1], path[i], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectEdge returns the index of the edge to descend into
+func selectEdge(edges []EdgeStat, policy string) int {
+	parentVisits := 0
+	for _, edge := range edges {
+		parentVisits += edge.Visits
+	}
+
+	best, bestScore := 0, math.Inf(-1)
+	for i, edge := range edges {
+		var score float64
+		switch {
+		case policy == "puct":
+			score = edge.MeanValue() + puctExploration*edge.Prior*math.Sqrt(float64(parentVisits+1))/float64(1+edge.Visits)
+		case edge.Visits == 0:
+			// Unvisited moves are tried first, most promising first
+			score = unvisitedBonus + edge.Prior
+		default:
+			score = edge.MeanValue() + uctExploration*math.Sqrt(math.Log(float64(parentVisits))/float64(edge.Visits))
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+// expandNode stores the legal children of board with their priors. Children
+// already stored are reused and existing edges keep their statistics.
+func expandNode(store PositionStore, variant components.Variant, parentID int, board *components.ChessBoard) error {
+	children := components.LegalMoves(variant, board)
+	priors := movePriors(children)
+	sans := board.SANs()
+
+	childIDs, err := store.PutPositions(children)
+	if err != nil {
+		return err
+	}
+	for i, child := range children {
+		if err := store.AddEdge(parentID, childIDs[i], newMoveLabel(board, sans, child.LastMove)); err != nil {
+			return err
+		}
+		if err := store.SetPrior(parentID, childIDs[i], priors[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// movePriors turns the static evaluation of each child into a probability
+// with a softmax, so PUCT looks at the better moves first
+func movePriors(children []*components.ChessBoard) []float64 {
+	priors := make([]float64, len(children))
+	best := math.Inf(-1)
+	for i, child := range children {
+		priors[i] = float64(-child.EvaluateRelative()) / priorTemperature
+		best = math.Max(best, priors[i])
+	}
+
+	total := 0.0
+	for i := range priors {
+		priors[i] = math.Exp(priors[i] - best)
+		total += priors[i]
+	}
+	for i := range priors {
+		priors[i] /= total
+	}
+	return priors
+}
+
+// runPlayout scores board for its side to move. Random playouts play random
+// legal moves until the game ends or maxPlayoutPlies is reached; eval
+// playouts replace the rollout with a quiescence search.
+func runPlayout(variant components.Variant, board components.ChessBoard, playout string, rng *rand.Rand) float64 {
+	if playout == "eval" {
+		return evaluationValue(search.Quiesce(board))
+	}
+
+	sign := 1.0
+	for ply := 0; ply < maxPlayoutPlies; ply++ {
+		children := components.LegalMoves(variant, &board)
+		if result := variant.Result(&board, children); result != components.Ongoing {
+			return sign * float64(result.ForSide(board.NextTurn))
+		}
+		board = *children[rng.Intn(len(children))]
+		sign = -sign
+	}
+	return sign * evaluationValue(board.EvaluateRelative())
+}
+
+// evaluationValue maps a centipawn score onto [-1, 1]
+func evaluationValue(score int) float64 {
+	return math.Tanh(float64(score) / valueScale)
+}
+
+// printMCTSRoot lists the root moves by visit count
+func printMCTSRoot(store PositionStore, rootID int) error {
+	edges, err := store.EdgeStats(rootID)
+	if err != nil {
+		return err
+	}
+	moves, err := store.Moves(rootID)
+	if err != nil {
+		return err
+	}
+	total := 0
+	for _, edge := range edges {
+		total += edge.Visits
+	}
+
+	sort.SliceStable(edges, func(i, j int) bool {
+		return edges[i].Visits > edges[j].Visits
+	})
+	for _, edge := range edges {
+		if edge.Visits == 0 {
+			continue
+		}
+		move := "-"
+		if label, ok := moves[edge.ChildID]; ok {
+			move = label.String()
+		}
+		fmt.Printf("%-6s child %d: %d visits (%.1f%%), value %+.3f, prior %.3f\n",
+			move, edge.ChildID, edge.Visits,
+			100*float64(edge.Visits)/float64(total), edge.MeanValue(), edge.Prior)
+	}
+	return nil
+}
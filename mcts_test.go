@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestSelectEdgeTriesUnvisitedByPrior(t *testing.T) {
+	edges := []EdgeStat{
+		{ChildID: 1, Visits: 3, ValueSum: 3, Prior: 0.1},
+		{ChildID: 2, Prior: 0.2},
+		{ChildID: 3, Prior: 0.45},
+		{ChildID: 4, Prior: 0.25},
+	}
+	for _, want := range []int{2, 3, 1} {
+		got := selectEdge(edges, "uct")
+		if got != want {
+			t.Fatalf("selected edge %d (prior %v), want %d (prior %v)", got, edges[got].Prior, want, edges[want].Prior)
+		}
+		edges[got].Visits++
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// MemoryStore is a PositionStore kept in maps, for tests and small
+// explorations that do not need chess.db. Positions are kept in their stored
+// JSON form, so they deduplicate exactly like SQLiteStore.
+type MemoryStore struct {
+	mu       sync.Mutex
+	states   []string // stored JSON, position ID i+1 at index i
+	ids      map[string]int
+	metadata map[int]NodeMetadata
+	children map[int][]int
+	parents  map[int][]int
+	edges    map[[2]int]*memoryEdge
+	frontier map[int][]int // positions waiting at each depth
+	size     int64
+}
+
+// memoryEdge is an edge's label and its MCTS statistics, which count once
+// expanded is set
+type memoryEdge struct {
+	label    MoveLabel
+	stat     EdgeStat
+	expanded bool
+}
+
+// Rough bytes an edge or a frontier entry takes, for Size
+const memoryEntrySize = 16
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		ids:      make(map[string]int),
+		metadata: make(map[int]NodeMetadata),
+		children: make(map[int][]int),
+		parents:  make(map[int][]int),
+		edges:    make(map[[2]int]*memoryEdge),
+		frontier: make(map[int][]int),
+	}
+}
+
+func (s *MemoryStore) PutPositions(boards []*components.ChessBoard) ([]int, error) {
+	ids := make([]int, len(boards))
+	for i, board := range boards {
+		data, err := json.Marshal(board)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling state: %v", err)
+		}
+
+		s.mu.Lock()
+		id, ok := s.ids[string(data)]
+		if !ok {
+			s.states = append(s.states, string(data))
+			id = len(s.states)
+			s.ids[string(data)] = id
+			s.size += int64(len(data))
+		}
+		s.mu.Unlock()
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func (s *MemoryStore) Position(id int) (*components.ChessBoard, error) {
+	s.mu.Lock()
+	if id < 1 || id > len(s.states) {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("no state with ID %d", id)
+	}
+	data := s.states[id-1]
+	s.mu.Unlock()
+
+	var board components.ChessBoard
+	if err := json.Unmarshal([]byte(data), &board); err != nil {
+		return nil, fmt.Errorf("error unmarshaling state %d: %v", id, err)
+	}
+	return &board, nil
+}
+
+func (s *MemoryStore) AddEdge(parentID, childID int, label MoveLabel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	edge := [2]int{parentID, childID}
+	if stored, ok := s.edges[edge]; ok {
+		if stored.label == (MoveLabel{}) {
+			stored.label = label
+		}
+		return nil
+	}
+	s.edges[edge] = &memoryEdge{label: label, stat: EdgeStat{ChildID: childID}}
+	s.children[parentID] = append(s.children[parentID], childID)
+	s.parents[childID] = append(s.parents[childID], parentID)
+	s.size += memoryEntrySize
+	return nil
+}
+
+func (s *MemoryStore) Children(parentID int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := sortedIDs(s.children[parentID])
+	sort.SliceStable(ids, func(i, j int) bool {
+		return s.edges[[2]int{parentID, ids[i]}].stat.Visits > s.edges[[2]int{parentID, ids[j]}].stat.Visits
+	})
+	return ids, nil
+}
+
+func (s *MemoryStore) Moves(parentID int) (map[int]MoveLabel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	moves := make(map[int]MoveLabel)
+	for _, childID := range s.children[parentID] {
+		if label := s.edges[[2]int{parentID, childID}].label; label != (MoveLabel{}) {
+			moves[childID] = label
+		}
+	}
+	return moves, nil
+}
+
+func (s *MemoryStore) Parents(childID int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedIDs(s.parents[childID]), nil
+}
+
+func (s *MemoryStore) Enqueue(depth int, ids []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frontier[depth] = append(s.frontier[depth], ids...)
+	s.size += int64(len(ids)) * memoryEntrySize
+	return nil
+}
+
+func (s *MemoryStore) Dequeue(depth, limit int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.frontier[depth]
+	n := min(limit, len(queue))
+	ids := append([]int(nil), queue[:n]...)
+	s.frontier[depth] = queue[n:]
+	if len(s.frontier[depth]) == 0 {
+		delete(s.frontier, depth)
+	}
+	s.size -= int64(n) * memoryEntrySize
+	return ids, nil
+}
+
+func (s *MemoryStore) Pending(depth int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.frontier[depth]), nil
+}
+
+func (s *MemoryStore) Metadata(id int) (NodeMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || id > len(s.states) {
+		return NodeMetadata{}, fmt.Errorf("no state with ID %d", id)
+	}
+	return s.metadata[id], nil
+}
+
+func (s *MemoryStore) SetMetadata(id int, meta NodeMetadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id < 1 || id > len(s.states) {
+		return fmt.Errorf("no state with ID %d", id)
+	}
+
+	stored := s.metadata[id]
+	if meta.MinimaxValue != nil {
+		value := *meta.MinimaxValue
+		stored.MinimaxValue = &value
+	}
+	if meta.BestChild != nil {
+		child := *meta.BestChild
+		stored.BestChild = &child
+	}
+	if meta.ProofStatus != nil {
+		status := *meta.ProofStatus
+		stored.ProofStatus = &status
+	}
+	s.metadata[id] = stored
+	return nil
+}
+
+func (s *MemoryStore) EdgeStats(parentID int) ([]EdgeStat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats []EdgeStat
+	for _, childID := range sortedIDs(s.children[parentID]) {
+		if edge := s.edges[[2]int{parentID, childID}]; edge.expanded {
+			stats = append(stats, edge.stat)
+		}
+	}
+	return stats, nil
+}
+
+func (s *MemoryStore) SetPrior(parentID, childID int, prior float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	edge, ok := s.edges[[2]int{parentID, childID}]
+	if !ok {
+		return fmt.Errorf("no edge %d -> %d", parentID, childID)
+	}
+	edge.stat.Prior = prior
+	edge.expanded = true
+	return nil
+}
+
+func (s *MemoryStore) AddVisit(parentID, childID int, value float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	edge, ok := s.edges[[2]int{parentID, childID}]
+	if !ok {
+		return fmt.Errorf("no edge %d -> %d", parentID, childID)
+	}
+	edge.stat.Visits++
+	edge.stat.ValueSum += value
+	return nil
+}
+
+// Size is an estimate: the stored JSON plus a fixed amount per edge and
+// frontier entry
+func (s *MemoryStore) Size() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size, nil
+}
+
+func sortedIDs(ids []int) []int {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	return sorted
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/search"
+)
+
+type levelNode struct {
+	id    int
+	board components.ChessBoard
+}
+
+// backPropagateMinimax combines the leaf evaluations of an explored tree into
+// a negamax value and best child for every node reachable from rootID. Each
+// node is assigned its shortest distance from the root and levels are solved
+// from the deepest frontier upwards, so a node only looks at children one
+// level below it. Edges back up the tree (repeated positions) are ignored.
+//
+// Values are stored in board_states.minimax_value from the point of view of
+// the side to move. Illegal positions left behind by pseudo-legal exploration
+// get a NULL value so their parents skip them.
+func backPropagateMinimax(db *sql.DB, rootID int, variant components.Variant) error {
+	maxDepth, err := computeNodeDepths(db, rootID)
+	if err != nil {
+		return err
+	}
+
+	for depth := maxDepth; depth >= 0; depth-- {
+		solved, err := solveMinimaxLevel(db, variant, depth)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Depth %d: evaluated %d nodes\n", depth, solved)
+	}
+
+	var value, bestChild sql.NullInt64
+	err = db.QueryRow("SELECT minimax_value, best_child_id FROM board_states WHERE id = ?", rootID).Scan(&value, &bestChild)
+	if err != nil {
+		return fmt.Errorf("error reading root value: %v", err)
+	}
+	if value.Valid {
+		fmt.Printf("Root %d value: %s", rootID, components.FormatScore(int(value.Int64)))
+		if bestChild.Valid {
+			fmt.Printf(", best child %d", bestChild.Int64)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// computeNodeDepths fills node_depths with the BFS level of every node
+// reachable from rootID and returns the deepest level found
+func computeNodeDepths(db *sql.DB, rootID int) (int, error) {
+	_, err := db.Exec(`
+		DROP TABLE IF EXISTS node_depths;
+		CREATE TABLE node_depths (
+			id INTEGER PRIMARY KEY,
+			depth INTEGER NOT NULL
+		);
+		CREATE INDEX idx_node_depths_depth ON node_depths(depth, id);
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("error creating node_depths table: %v", err)
+	}
+
+	if _, err := db.Exec("INSERT INTO node_depths (id, depth) VALUES (?, 0)", rootID); err != nil {
+		return 0, fmt.Errorf("error storing root depth: %v", err)
+	}
+
+	depth := 0
+	for {
+		// INSERT OR IGNORE keeps the shallowest depth of transposed nodes
+		result, err := db.Exec(`
+			INSERT OR IGNORE INTO node_depths (id, depth)
+			SELECT r.child_id, ?
+			FROM node_relations r
+			JOIN node_depths d ON d.id = r.parent_id
+			WHERE d.depth = ?
+		`, depth+1, depth)
+		if err != nil {
+			return 0, fmt.Errorf("error computing depth %d: %v", depth+1, err)
+		}
+		added, err := result.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("error counting nodes at depth %d: %v", depth+1, err)
+		}
+		if added == 0 {
+			return depth, nil
+		}
+		depth++
+	}
+}
+
+// solveMinimaxLevel computes values for every node at the given depth, reading
+// the already solved values of their children one level deeper
+func solveMinimaxLevel(db *sql.DB, variant components.Variant, depth int) (int, error) {
+	solved := 0
+	lastID := -1
+	batchSize := 1000
+
+	for {
+		nodes, err := loadLevelNodes(db, depth, lastID, batchSize)
+		if err != nil {
+			return solved, err
+		}
+		if len(nodes) == 0 {
+			return solved, nil
+		}
+		lastID = nodes[len(nodes)-1].id
+
+		tx, err := db.Begin()
+		if err != nil {
+			return solved, fmt.Errorf("failed to begin transaction: %v", err)
+		}
+
+		childStmt, err := tx.Prepare(`
+			SELECT r.child_id, b.minimax_value
+			FROM node_relations r
+			JOIN node_depths d ON d.id = r.child_id
+			JOIN board_states b ON b.id = r.child_id
+			WHERE r.parent_id = ? AND d.depth = ? AND b.minimax_value IS NOT NULL
+		`)
+		if err != nil {
+			tx.Rollback()
+			return solved, fmt.Errorf("failed to prepare child query: %v", err)
+		}
+
+		updateStmt, err := tx.Prepare("UPDATE board_states SET minimax_value = ?, best_child_id = ? WHERE id = ?")
+		if err != nil {
+			childStmt.Close()
+			tx.Rollback()
+			return solved, fmt.Errorf("failed to prepare update: %v", err)
+		}
+
+		for _, node := range nodes {
+			value, bestChild, err := evaluateLevelNode(childStmt, variant, node, depth)
+			if err != nil {
+				childStmt.Close()
+				updateStmt.Close()
+				tx.Rollback()
+				return solved, err
+			}
+			if _, err := updateStmt.Exec(value, bestChild, node.id); err != nil {
+				childStmt.Close()
+				updateStmt.Close()
+				tx.Rollback()
+				return solved, fmt.Errorf("failed to store value for node %d: %v", node.id, err)
+			}
+			solved++
+		}
+
+		childStmt.Close()
+		updateStmt.Close()
+		if err := tx.Commit(); err != nil {
+			return solved, fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}
+}
+
+func loadLevelNodes(db *sql.DB, depth, afterID, limit int) ([]levelNode, error) {
+	rows, err := db.Query(`
+		SELECT d.id, b.state
+		FROM node_depths d
+		JOIN board_states b ON b.id = d.id
+		WHERE d.depth = ? AND d.id > ?
+		ORDER BY d.id
+		LIMIT ?
+	`, depth, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying nodes at depth %d: %v", depth, err)
+	}
+	defer rows.Close()
+
+	var nodes []levelNode
+	for rows.Next() {
+		var node levelNode
+		var data string
+		if err := rows.Scan(&node.id, &data); err != nil {
+			return nil, fmt.Errorf("error scanning node: %v", err)
+		}
+		if err := json.Unmarshal([]byte(data), &node.board); err != nil {
+			return nil, fmt.Errorf("error unmarshaling state %d: %v", node.id, err)
+		}
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over nodes: %v", err)
+	}
+	return nodes, nil
+}
+
+// evaluateLevelNode returns the negamax value and best child of a node.
+// The end of the game under the variant's rules takes precedence over
+// anything stored below the node; nodes without solved children are scored
+// by a quiescence search so frontier values do not stop in the middle of an
+// exchange.
+func evaluateLevelNode(childStmt *sql.Stmt, variant components.Variant, node levelNode, depth int) (sql.NullInt64, sql.NullInt64, error) {
+	var value, bestChild sql.NullInt64
+
+	if !variant.IsLegal(&node.board) {
+		return value, bestChild, nil
+	}
+
+	if result := components.GameResult(variant, &node.board); result != components.Ongoing {
+		value = sql.NullInt64{Int64: int64(result.ForSide(node.board.NextTurn) * components.MateScore), Valid: true}
+		return value, bestChild, nil
+	}
+
+	// Tablebase positions are exact whatever lies below them
+	if v, ok := tablebases.Probe(&node.board); ok {
+		value = sql.NullInt64{Int64: int64(v.Score(0)), Valid: true}
+		return value, bestChild, nil
+	}
+
+	rows, err := childStmt.Query(node.id, depth+1)
+	if err != nil {
+		return value, bestChild, fmt.Errorf("error querying children of %d: %v", node.id, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var childID int
+		var childValue int
+		if err := rows.Scan(&childID, &childValue); err != nil {
+			return value, bestChild, fmt.Errorf("error scanning child value: %v", err)
+		}
+		score := components.ParentScore(childValue)
+		if !value.Valid || int64(score) > value.Int64 {
+			value = sql.NullInt64{Int64: int64(score), Valid: true}
+			bestChild = sql.NullInt64{Int64: int64(childID), Valid: true}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return value, bestChild, fmt.Errorf("error iterating over children: %v", err)
+	}
+
+	if !value.Valid {
+		value = sql.NullInt64{Int64: int64(search.Quiesce(node.board)), Valid: true}
+	}
+	return value, bestChild, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/search"
+)
+
+// runProve answers whether the side to move in a stored position can force
+// mate: prove [nodeID] [maxNodes]. A proof or disproof is stored as a
+// subtree of the position, with every node of it marked in proof_status, so
+// it can be walked with browse.
+func runProve(db *sql.DB, args []string) error {
+	rootID, err := rootIDFromArgs(db, args)
+	if err != nil {
+		return err
+	}
+	prover := search.Prover{Tablebases: tablebases}
+	if len(args) > 1 {
+		if prover.MaxNodes, err = strconv.Atoi(args[1]); err != nil || prover.MaxNodes < 1 {
+			return fmt.Errorf("invalid node budget %q", args[1])
+		}
+	}
+
+	store := NewSQLiteStore(db)
+	board, err := store.Position(rootID)
+	if err != nil {
+		return err
+	}
+	prover.Variant = components.VariantOf(board)
+	board.ToString()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	start := time.Now()
+	result := prover.Prove(ctx, *board)
+	fmt.Printf("%s after %d nodes in %s\n", result.Status, result.Nodes, time.Since(start).Round(time.Millisecond))
+
+	attacker := map[bool]string{true: "White", false: "Black"}[board.NextTurn]
+	switch result.Status {
+	case search.Unknown:
+		fmt.Printf("Could not decide whether %s forces mate within the node budget\n", attacker)
+		return nil
+	case search.Disproven:
+		fmt.Printf("%s cannot force mate\n", attacker)
+	case search.Proven:
+		tree := result.Root.ProofTree()
+		fmt.Printf("%s forces mate in %d: %s\n", attacker, (tree.MateIn()+1)/2, formatPV(tree.MainLine()))
+	}
+
+	stored, err := storeProofTree(store, rootID, result.Root.ProofTree(), attacker)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Stored %d positions of the proof tree under node %d\n", stored, rootID)
+	return nil
+}
+
+// storeProofTree saves the positions and moves of a proof tree below rootID
+// and marks each of them with whether the attacker forces mate from there.
+// Positions already stored are reused. It returns the number of nodes
+// stored.
+func storeProofTree(store PositionStore, rootID int, tree *search.ProofNode, attacker string) (int, error) {
+	stored := 0
+	var save func(id int, node *search.ProofNode) error
+	save = func(id int, node *search.ProofNode) error {
+		status := attacker + " forces mate"
+		if node.Status() == search.Disproven {
+			status = attacker + " cannot force mate"
+		}
+		if err := store.SetMetadata(id, NodeMetadata{ProofStatus: &status}); err != nil {
+			return err
+		}
+		stored++
+		if len(node.Children) == 0 {
+			return nil
+		}
+
+		boards := make([]*components.ChessBoard, len(node.Children))
+		for i, child := range node.Children {
+			boards[i] = child.Board
+		}
+		childIDs, err := store.PutPositions(boards)
+		if err != nil {
+			return err
+		}
+		sans := node.Board.SANs()
+		for i, child := range node.Children {
+			if err := store.AddEdge(id, childIDs[i], newMoveLabel(node.Board, sans, child.Move)); err != nil {
+				return err
+			}
+			if err := save(childIDs[i], child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := save(rootID, tree); err != nil {
+		return 0, err
+	}
+	return stored, nil
+}
@@ -0,0 +1,206 @@
+package search
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// mateKey is mixed into position hashes so mate solver entries never collide
+// with search or perft entries when they share a table
+const mateKey = 0x6d61746500000000
+
+// MateResult is the verdict on one root move of a mate-in-N problem
+type MateResult struct {
+	Move components.Move
+
+	// MateIn is the fastest forced mate after Move, in moves including Move
+	// itself, or 0 if Move does not force mate within N. Such a move is a try.
+	MateIn int
+
+	// Refutation is a defence to a try that avoids mate within N. It is null
+	// when the try stalemates.
+	Refutation components.Move
+	Stalemate  bool
+}
+
+// IsKey reports whether the move forces mate within N
+func (r MateResult) IsKey() bool {
+	return r.MateIn > 0
+}
+
+// MateSolution holds the verdicts on all root moves of a problem
+type MateSolution struct {
+	Results []MateResult
+	Nodes   uint64
+}
+
+// Keys returns the moves forcing mate within N, fastest first
+func (s MateSolution) Keys() []MateResult {
+	var keys []MateResult
+	for _, result := range s.Results {
+		if result.IsKey() {
+			keys = append(keys, result)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].MateIn < keys[j].MateIn })
+	return keys
+}
+
+// Tries returns the moves that fail, each with its refutation
+func (s MateSolution) Tries() []MateResult {
+	var tries []MateResult
+	for _, result := range s.Results {
+		if !result.IsKey() {
+			tries = append(tries, result)
+		}
+	}
+	return tries
+}
+
+type mateSolver struct {
+	ctx   context.Context
+	tt    *TranspositionTable
+	nodes atomic.Uint64
+}
+
+// SolveMate finds every move with which the side to move forces mate in at
+// most n moves of standard chess, and a refutation for each other move. Root
+// moves are solved in parallel, all goroutines sharing tt if it is not nil.
+// If ctx is cancelled the results are incomplete.
+func SolveMate(ctx context.Context, board components.ChessBoard, n int, tt *TranspositionTable) MateSolution {
+	s := &mateSolver{ctx: ctx, tt: tt}
+	if n < 1 {
+		return MateSolution{}
+	}
+
+	children := components.LegalMoves(components.Standard{}, &board)
+	results := make([]MateResult, len(children))
+	workers := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+
+	for i, child := range children {
+		wg.Add(1)
+		go func(i int, child *components.ChessBoard) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			results[i] = s.solveRootMove(child, n)
+		}(i, child)
+	}
+	wg.Wait()
+	return MateSolution{Results: results, Nodes: s.nodes.Load()}
+}
+
+// solveRootMove finds the fastest mate after the root move leading to child,
+// deepening one move at a time, or a refutation if there is none within n
+func (s *mateSolver) solveRootMove(child *components.ChessBoard, n int) MateResult {
+	result := MateResult{Move: child.LastMove}
+	for moves := 1; moves <= n && s.ctx.Err() == nil; moves++ {
+		refutation, refuted := s.refute(child, moves-1)
+		if !refuted {
+			result.MateIn = moves
+			return result
+		}
+		if refutation.IsNull() {
+			result.Stalemate = true
+			return result
+		}
+		result.Refutation = refutation
+	}
+	return result
+}
+
+// refute looks for a defence in board, with the defender to move, after
+// which the attacker cannot mate in n moves. Stalemate refutes with a null
+// move; checkmate cannot be refuted.
+func (s *mateSolver) refute(board *components.ChessBoard, n int) (components.Move, bool) {
+	defences := components.LegalMoves(components.Standard{}, board)
+	if len(defences) == 0 {
+		return components.Move{}, !board.IsInCheck(board.NextTurn)
+	}
+	orderChecksFirst(board, defences)
+	for _, defence := range defences {
+		if !s.mates(defence, n) {
+			return defence.LastMove, true
+		}
+	}
+	return components.Move{}, false
+}
+
+// mates reports whether the side to move in board forces mate in at most n
+// moves. A mating move always gives check, so the last move only tries
+// checks, and checks are tried first elsewhere as they leave the fewest
+// defences.
+func (s *mateSolver) mates(board *components.ChessBoard, n int) bool {
+	if n < 1 || s.ctx.Err() != nil {
+		return false
+	}
+	s.nodes.Add(1)
+
+	var key uint64
+	if s.tt != nil {
+		key = board.Hash() ^ mateKey
+		if entry, ok := s.tt.Probe(key); ok {
+			if entry.Score > 0 && entry.Score <= n {
+				return true
+			}
+			if entry.Score == 0 && entry.Depth >= n {
+				return false
+			}
+		}
+	}
+
+	found := false
+	children := components.LegalMoves(components.Standard{}, board)
+	checks := orderChecksFirst(board, children)
+	for i, child := range children {
+		if n == 1 && i >= checks {
+			break
+		}
+		if _, refuted := s.refute(child, n-1); !refuted {
+			found = true
+			break
+		}
+	}
+
+	if s.tt != nil && s.ctx.Err() == nil {
+		entry := Entry{Key: key, Depth: n, Bound: BoundExact}
+		if found {
+			entry.Score = n
+		}
+		s.tt.Store(entry)
+	}
+	return found
+}
+
+// orderChecksFirst sorts children so that checks come first and captures
+// next, keeping the generation order otherwise. It returns the number of
+// checks.
+func orderChecksFirst(board *components.ChessBoard, children []*components.ChessBoard) int {
+	checks := 0
+	scored := make([]scoredChild, len(children))
+	for i, child := range children {
+		scored[i].board = child
+		switch {
+		case child.IsInCheck(child.NextTurn):
+			checks++
+		case isCapture(board, child.LastMove):
+			scored[i].score = 1
+		default:
+			scored[i].score = 2
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score < scored[j].score
+	})
+	for i := range scored {
+		children[i] = scored[i].board
+	}
+	return checks
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSolveMate(t *testing.T) {
+	for _, tt := range []*TranspositionTable{nil, NewTranspositionTable(16)} {
+		solution := SolveMate(context.Background(), parseFEN(t, mateInTwo), 2, tt)
+
+		keys := solution.Keys()
+		if len(keys) != 1 || keys[0].Move.UCI() != "c6c7" || keys[0].MateIn != 2 {
+			t.Fatalf("keys %+v, want only c6c7 mating in 2", keys)
+		}
+
+		tries := make(map[string]MateResult)
+		for _, try := range solution.Tries() {
+			tries[try.Move.UCI()] = try
+		}
+		if len(tries) != len(solution.Results)-1 {
+			t.Errorf("%d tries for %d moves", len(tries), len(solution.Results))
+		}
+		// Ra1+ fails to Kb8, and Rb7 stalemates
+		if try := tries["b1a1"]; try.Refutation.UCI() != "a8b8" || try.Stalemate {
+			t.Errorf("b1a1 refuted by %s, want a8b8", try.Refutation.UCI())
+		}
+		if try := tries["b1b7"]; !try.Stalemate || !try.Refutation.IsNull() {
+			t.Errorf("b1b7 is %+v, want stalemate", try)
+		}
+	}
+
+	// Within one move there is no mate at all
+	if keys := SolveMate(context.Background(), parseFEN(t, mateInTwo), 1, nil).Keys(); len(keys) != 0 {
+		t.Errorf("mate in one keys %+v", keys)
+	}
+}
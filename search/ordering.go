@@ -0,0 +1,115 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// Ordering scores, highest searched first. Captures are ranked by most
+// valuable victim, then least valuable attacker (MVV-LVA).
+const (
+	scoreHashMove  = 1 << 30
+	scoreCapture   = 1 << 24
+	scorePromotion = 1 << 23
+	scoreKiller    = 1 << 22
+	maxHistory     = scoreKiller - 1
+)
+
+// moveOrdering keeps the per-search heuristics for quiet moves: two killer
+// moves per ply and a history table indexed by side, from and to square
+type moveOrdering struct {
+	killers [maxPly][2]components.Move
+	history [2][components.Ranks][components.MaxFiles][components.Ranks][components.MaxFiles]int
+}
+
+func (o *moveOrdering) clear() {
+	*o = moveOrdering{}
+}
+
+type scoredChild struct {
+	board *components.ChessBoard
+	score int
+}
+
+// order sorts children from most to least promising
+func (o *moveOrdering) order(board *components.ChessBoard, children []*components.ChessBoard, hashMove components.Move, ply int) {
+	scored := make([]scoredChild, len(children))
+	for i, child := range children {
+		scored[i] = scoredChild{board: child, score: o.score(board, child, hashMove, ply)}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+	for i := range scored {
+		children[i] = scored[i].board
+	}
+}
+
+func (o *moveOrdering) score(board, child *components.ChessBoard, hashMove components.Move, ply int) int {
+	move := child.LastMove
+	switch {
+	case !hashMove.IsNull() && move == hashMove:
+		return scoreHashMove
+	case isCapture(board, move):
+		victim := components.PieceValue(board.CapturedPiece(move))
+		attacker := components.PieceValue(board.Board[move.From.Y][move.From.X])
+		return scoreCapture + victim*16 - attacker/16 + promotionValue(move)
+	case move.Promotion != 0:
+		return scorePromotion + promotionValue(move)
+	case ply < maxPly && move == o.killers[ply][0]:
+		return scoreKiller + 1
+	case ply < maxPly && move == o.killers[ply][1]:
+		return scoreKiller
+	default:
+		return o.history[colorIndex(board.NextTurn)][move.From.Y][move.From.X][move.To.Y][move.To.X]
+	}
+}
+
+// recordCutoff updates killers and history after move caused a beta cutoff.
+// Captures and promotions are already ordered well and are not recorded.
+func (o *moveOrdering) recordCutoff(board *components.ChessBoard, move components.Move, depth, ply int) {
+	if isCapture(board, move) || move.Promotion != 0 || ply >= maxPly {
+		return
+	}
+
+	if o.killers[ply][0] != move {
+		o.killers[ply][1] = o.killers[ply][0]
+		o.killers[ply][0] = move
+	}
+
+	entry := &o.history[colorIndex(board.NextTurn)][move.From.Y][move.From.X][move.To.Y][move.To.X]
+	*entry += depth * depth
+	if *entry > maxHistory {
+		// Halve everything so older cutoffs fade instead of saturating
+		for side := range o.history {
+			for fy := range o.history[side] {
+				for fx := range o.history[side][fy] {
+					for ty := range o.history[side][fy][fx] {
+						for tx := range o.history[side][fy][fx][ty] {
+							o.history[side][fy][fx][ty][tx] /= 2
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func isCapture(board *components.ChessBoard, move components.Move) bool {
+	return board.CapturedPiece(move) != nil
+}
+
+func promotionValue(move components.Move) int {
+	if move.Promotion == 0 {
+		return 0
+	}
+	return components.PieceValueOf(move.Promotion)
+}
+
+func colorIndex(color bool) int {
+	if color {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,55 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+func TestMoveOrder(t *testing.T) {
+	// Pawn and queen can take the rook on d5, pawn and knight the pawn on b5
+	board := parseFEN(t, "4k3/8/8/1p1r4/2P5/N7/8/3QK3 w - - 0 1")
+	children := components.LegalMoves(components.Standard{}, &board)
+	hashMove := components.Move{From: components.Coordinates{X: 4, Y: 0}, To: components.Coordinates{X: 5, Y: 1}} // Kf2
+
+	var ordering moveOrdering
+	ordering.order(&board, children, hashMove, 0)
+	want := []string{"e1f2", "c4d5", "d1d5", "c4b5", "a3b5"}
+	for i, move := range want {
+		if got := children[i].LastMove.UCI(); got != move {
+			t.Errorf("move %d is %s, want %s", i, got, move)
+		}
+	}
+	for _, child := range children[len(want):] {
+		if isCapture(&board, child.LastMove) {
+			t.Errorf("capture %s ordered after the quiet moves began", child.LastMove.UCI())
+		}
+	}
+
+	// A killer comes before the other quiet moves
+	quiet := children[len(want)+3]
+	ordering.recordCutoff(&board, quiet.LastMove, 4, 0)
+	ordering.order(&board, children, components.Move{}, 0)
+	if got := children[4].LastMove; got != quiet.LastMove {
+		t.Errorf("killer %s not first of the quiet moves, found %s", quiet.LastMove.UCI(), got.UCI())
+	}
+}
+
+func TestOrderingSearchesFewerNodes(t *testing.T) {
+	board := parseFEN(t, perftPositions[4].fen)
+	var nodes [2]uint64
+	var scores [2]int
+	for i, ordered := range []bool{false, true} {
+		engine := NewEngine()
+		engine.MoveOrdering = ordered
+		result := engine.Search(context.Background(), board, Limits{Depth: 3})
+		nodes[i], scores[i] = result.Nodes, result.Score
+	}
+	if scores[0] != scores[1] {
+		t.Errorf("scores %d unordered and %d ordered", scores[0], scores[1])
+	}
+	if nodes[1] >= nodes[0] {
+		t.Errorf("ordered search took %d nodes, unordered %d", nodes[1], nodes[0])
+	}
+}
@@ -0,0 +1,80 @@
+package search
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// perftKey is mixed into position hashes so perft counts never collide with
+// search entries when both share a table
+const perftKey = 0x7065726674000000
+
+// DivideResult is the number of leaf nodes below one root move
+type DivideResult struct {
+	Move  components.Move
+	Nodes uint64
+}
+
+// Perft counts the leaf nodes of the legal move tree to the given depth under
+// the board's variant. If tt is not nil, subtree counts are cached in it.
+func Perft(board components.ChessBoard, depth int, tt *TranspositionTable) uint64 {
+	return perft(components.VariantOf(&board), &board, depth, tt)
+}
+
+// PerftDivide counts the leaf nodes below each root move. Root moves are
+// counted in parallel, all goroutines sharing tt.
+func PerftDivide(board components.ChessBoard, depth int, tt *TranspositionTable) []DivideResult {
+	if depth < 1 {
+		return nil
+	}
+
+	variant := components.VariantOf(&board)
+	children := components.LegalMoves(variant, &board)
+	results := make([]DivideResult, len(children))
+	workers := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+
+	for i, child := range children {
+		wg.Add(1)
+		go func(i int, child *components.ChessBoard) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+
+			results[i] = DivideResult{Move: child.LastMove, Nodes: perft(variant, child, depth-1, tt)}
+		}(i, child)
+	}
+	wg.Wait()
+	return results
+}
+
+func perft(variant components.Variant, board *components.ChessBoard, depth int, tt *TranspositionTable) uint64 {
+	if depth == 0 {
+		return 1
+	}
+
+	var key uint64
+	if tt != nil && depth > 1 {
+		key = board.Hash() ^ perftKey
+		if entry, ok := tt.Probe(key); ok && entry.Depth == depth {
+			return uint64(entry.Score)
+		}
+	}
+
+	children := components.LegalMoves(variant, board)
+	if depth == 1 {
+		return uint64(len(children))
+	}
+
+	var nodes uint64
+	for _, child := range children {
+		nodes += perft(variant, child, depth-1, tt)
+	}
+
+	if tt != nil {
+		tt.Store(Entry{Key: key, Depth: depth, Bound: BoundExact, Score: int(nodes)})
+	}
+	return nodes
+}
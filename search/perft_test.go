@@ -0,0 +1,50 @@
+package search
+
+import "testing"
+
+// perftPositions are the standard perft reference positions with their
+// counts from depth 1
+var perftPositions = []struct {
+	name  string
+	fen   string
+	nodes []uint64
+}{
+	{"start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []uint64{20, 400, 8902, 197281}},
+	{"kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862}},
+	{"position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []uint64{14, 191, 2812, 43238}},
+	{"position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []uint64{6, 264, 9467}},
+	{"position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []uint64{44, 1486, 62379}},
+}
+
+func TestPerft(t *testing.T) {
+	for _, withTT := range []bool{false, true} {
+		var tt *TranspositionTable
+		if withTT {
+			tt = NewTranspositionTable(16)
+		}
+		for _, position := range perftPositions {
+			board := parseFEN(t, position.fen)
+			for i, want := range position.nodes {
+				if got := Perft(board, i+1, tt); got != want {
+					t.Errorf("%s, TT %v: perft(%d) = %d, want %d", position.name, withTT, i+1, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestPerftDivide(t *testing.T) {
+	position := perftPositions[1]
+	board := parseFEN(t, position.fen)
+	results := PerftDivide(board, 3, NewTranspositionTable(16))
+	if len(results) != int(position.nodes[0]) {
+		t.Errorf("%d root moves, want %d", len(results), position.nodes[0])
+	}
+	var total uint64
+	for _, result := range results {
+		total += result.Nodes
+	}
+	if total != position.nodes[2] {
+		t.Errorf("divided counts add up to %d, want %d", total, position.nodes[2])
+	}
+}
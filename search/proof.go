@@ -0,0 +1,278 @@
+package search
+
+import (
+	"context"
+	"sort"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/tablebase"
+)
+
+// Proof and disproof numbers count the leaves that still have to be solved
+// to prove or disprove a node. pnInfinity marks a node that can no longer be
+// proven (or disproven).
+const pnInfinity = uint64(1) << 62
+
+// DefaultProofNodes is the node budget of a proof search when none is given
+const DefaultProofNodes = 100000
+
+// ProofStatus is the outcome of a proof-number search
+type ProofStatus int
+
+const (
+	Unknown   ProofStatus = iota // the budget ran out first
+	Proven                       // the side to move forces mate
+	Disproven                    // the side to move cannot force mate
+)
+
+func (s ProofStatus) String() string {
+	switch s {
+	case Proven:
+		return "proven"
+	case Disproven:
+		return "disproven"
+	default:
+		return "unknown"
+	}
+}
+
+// ProofNode is a position in the proof-number search tree. At OR nodes the
+// attacker, the side to move at the root, is to move; at AND nodes the
+// defender is.
+type ProofNode struct {
+	Board    *components.ChessBoard
+	Move     components.Move // move from the parent, null at the root
+	Proof    uint64
+	Disproof uint64
+	Children []*ProofNode
+
+	or       bool
+	expanded bool
+	parent   *ProofNode
+	hash     uint64
+
+	// Plies to the deepest leaf of the proof tree, set by ProofTree. For a
+	// proof this is the length of the mate, unless a tablebase ended it early.
+	mateIn int
+}
+
+// Status returns whether the node is solved for the attacker
+func (n *ProofNode) Status() ProofStatus {
+	switch {
+	case n.Proof == 0:
+		return Proven
+	case n.Disproof == 0:
+		return Disproven
+	}
+	return Unknown
+}
+
+// IsOr reports whether the attacker is to move at n
+func (n *ProofNode) IsOr() bool {
+	return n.or
+}
+
+// ProofResult is the outcome of Prove
+type ProofResult struct {
+	Status ProofStatus
+	Root   *ProofNode
+	Nodes  int // nodes created during the search
+}
+
+// Prover answers whether the side to move can force mate, or under variant
+// rules force a win, with a best-first proof-number search
+type Prover struct {
+	// MaxNodes bounds the size of the search tree, DefaultProofNodes if 0
+	MaxNodes int
+
+	// Tablebases, if set, solve positions with few pieces outright in
+	// standard chess
+	Tablebases *tablebase.Set
+
+	// Variant gives the rules; nil uses the variant of the board proven
+	Variant components.Variant
+
+	nodes    int
+	variant  components.Variant
+	attacker bool
+}
+
+// Prove searches until the root is proven, disproven, MaxNodes nodes have
+// been created or ctx is cancelled. Repeated positions count as draws, so
+// they disprove the line they appear in.
+func (p *Prover) Prove(ctx context.Context, board components.ChessBoard) ProofResult {
+	maxNodes := p.MaxNodes
+	if maxNodes <= 0 {
+		maxNodes = DefaultProofNodes
+	}
+	p.nodes = 1
+	p.variant = p.Variant
+	if p.variant == nil {
+		p.variant = components.VariantOf(&board)
+	}
+	p.attacker = board.NextTurn
+
+	root := &ProofNode{Board: &board, or: true, hash: board.Hash()}
+	p.evaluate(root)
+	for root.Proof != 0 && root.Disproof != 0 && p.nodes < maxNodes && ctx.Err() == nil {
+		node := mostProving(root)
+		p.expand(node)
+		for ; node != nil; node = node.parent {
+			node.update()
+		}
+	}
+
+	return ProofResult{Status: root.Status(), Root: root, Nodes: p.nodes}
+}
+
+// evaluate sets the numbers of a new leaf: solved if the game is over there,
+// otherwise one for both
+func (p *Prover) evaluate(n *ProofNode) {
+	n.Proof, n.Disproof = 1, 1
+
+	if _, standard := p.variant.(components.Standard); standard && p.Tablebases != nil {
+		if v, ok := p.Tablebases.Probe(n.Board); ok {
+			// The attacker wins when the side to move wins at OR nodes or
+			// loses at AND nodes
+			if (n.or && v.Win()) || (!n.or && v.Loss()) {
+				n.Proof, n.Disproof = 0, pnInfinity
+			} else {
+				n.Proof, n.Disproof = pnInfinity, 0
+			}
+			return
+		}
+	}
+
+	for ancestor := n.parent; ancestor != nil; ancestor = ancestor.parent {
+		if ancestor.hash == n.hash {
+			n.Proof, n.Disproof = pnInfinity, 0
+			return
+		}
+	}
+
+	if result := components.GameResult(p.variant, n.Board); result != components.Ongoing {
+		if result.ForSide(p.attacker) > 0 {
+			n.Proof, n.Disproof = 0, pnInfinity
+		} else {
+			n.Proof, n.Disproof = pnInfinity, 0
+		}
+	}
+}
+
+// mostProving follows the children that determine the root's numbers down to
+// the leaf whose expansion helps most
+func mostProving(n *ProofNode) *ProofNode {
+	for n.expanded {
+		var next *ProofNode
+		for _, child := range n.Children {
+			if next == nil ||
+				(n.or && child.Proof < next.Proof) ||
+				(!n.or && child.Disproof < next.Disproof) {
+				next = child
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+func (p *Prover) expand(n *ProofNode) {
+	for _, board := range components.LegalMoves(p.variant, n.Board) {
+		child := &ProofNode{
+			Board:  board,
+			Move:   board.LastMove,
+			or:     !n.or,
+			parent: n,
+			hash:   board.Hash(),
+		}
+		p.evaluate(child)
+		n.Children = append(n.Children, child)
+		p.nodes++
+	}
+	n.expanded = true
+}
+
+// update recomputes the numbers of an expanded node from its children
+func (n *ProofNode) update() {
+	if !n.expanded {
+		return
+	}
+	var sum, min uint64 = 0, pnInfinity
+	for _, child := range n.Children {
+		minor, major := child.Proof, child.Disproof
+		if !n.or {
+			minor, major = child.Disproof, child.Proof
+		}
+		if minor < min {
+			min = minor
+		}
+		if sum += major; sum > pnInfinity {
+			sum = pnInfinity
+		}
+	}
+	if n.or {
+		n.Proof, n.Disproof = min, sum
+	} else {
+		n.Proof, n.Disproof = sum, min
+	}
+}
+
+// ProofTree returns the part of the search tree that proves or disproves n:
+// for a proof the fastest mating move at OR nodes and every defence at AND
+// nodes, for a disproof the other way round. Nodes of the returned tree share
+// their boards with the search tree.
+func (n *ProofNode) ProofTree() *ProofNode {
+	status := n.Status()
+	if status == Unknown {
+		return nil
+	}
+	tree := *n
+	tree.Children = nil
+	if !n.expanded {
+		return &tree
+	}
+
+	// One child suffices where the winning side chooses
+	choose := (status == Proven) == n.or
+	var chosen *ProofNode
+	for _, child := range n.Children {
+		if child.Status() != status {
+			continue
+		}
+		sub := child.ProofTree()
+		if !choose {
+			tree.Children = append(tree.Children, sub)
+			if sub.mateIn+1 > tree.mateIn {
+				tree.mateIn = sub.mateIn + 1
+			}
+		} else if chosen == nil || sub.mateIn < chosen.mateIn {
+			chosen = sub
+		}
+	}
+	if chosen != nil {
+		tree.Children = []*ProofNode{chosen}
+		tree.mateIn = chosen.mateIn + 1
+	}
+
+	// The longest resistance comes first so MainLine shows it
+	sort.SliceStable(tree.Children, func(i, j int) bool {
+		return tree.Children[i].mateIn > tree.Children[j].mateIn
+	})
+	return &tree
+}
+
+// MateIn returns the length in plies of the longest line of a proof tree
+// against the best defence
+func (n *ProofNode) MateIn() int {
+	return n.mateIn
+}
+
+// MainLine follows the first child of a proof tree, the best attack against
+// the longest defence
+func (n *ProofNode) MainLine() []components.Move {
+	var line []components.Move
+	for node := n; len(node.Children) > 0; node = node.Children[0] {
+		line = append(line, node.Children[0].Move)
+	}
+	return line
+}
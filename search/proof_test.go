@@ -0,0 +1,94 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// mateInTwo is solved by Kc7 alone: Ka7 Ra1#. Rb7 is stalemate.
+const mateInTwo = "k7/8/2K5/8/8/8/8/1R6 w - - 0 1"
+
+func TestProveMateInOne(t *testing.T) {
+	prover := &Prover{}
+	result := prover.Prove(context.Background(), parseFEN(t, "k7/8/1K6/8/8/7Q/8/8 w - - 0 1"))
+	if result.Status != Proven {
+		t.Fatalf("status %s, want proven", result.Status)
+	}
+	tree := result.Root.ProofTree()
+	if line := uciLine(tree.MainLine()); tree.MateIn() != 1 || len(line) != 1 || (line[0] != "h3h8" && line[0] != "h3c8") {
+		t.Errorf("mate in %d plies with %v, want Qh8# or Qc8#", tree.MateIn(), line)
+	}
+}
+
+func TestProveMate(t *testing.T) {
+	prover := &Prover{}
+	result := prover.Prove(context.Background(), parseFEN(t, mateInTwo))
+	if result.Status != Proven {
+		t.Fatalf("status %s, want proven", result.Status)
+	}
+	tree := result.Root.ProofTree()
+	if tree.MateIn() < 3 || tree.MateIn()%2 == 0 {
+		t.Errorf("proof tree mates in %d plies", tree.MateIn())
+	}
+	checkProofTree(t, tree, Proven)
+
+	// The main line ends in checkmate
+	board := parseFEN(t, mateInTwo)
+	for _, move := range tree.MainLine() {
+		next, err := board.ApplyUCIMove(move.UCI())
+		if err != nil {
+			t.Fatalf("main line %v: %v", uciLine(tree.MainLine()), err)
+		}
+		board = *next
+	}
+	if components.GameResult(components.Standard{}, &board) != components.WhiteWins {
+		t.Errorf("main line %v does not mate", uciLine(tree.MainLine()))
+	}
+}
+
+// checkProofTree checks that a proof tree answers every defence at AND nodes
+// with one attacking move at OR nodes, down to solved leaves
+func checkProofTree(t *testing.T, node *ProofNode, status ProofStatus) {
+	t.Helper()
+	if node.Status() != status {
+		t.Fatalf("node after %s is %s, want %s", node.Move.UCI(), node.Status(), status)
+	}
+	moves := len(components.LegalMoves(components.Standard{}, node.Board))
+	switch {
+	case moves == 0:
+		return
+	case node.IsOr() && len(node.Children) != 1:
+		t.Errorf("OR node after %s has %d moves in the proof tree, want 1", node.Move.UCI(), len(node.Children))
+	case !node.IsOr() && len(node.Children) != moves:
+		t.Errorf("AND node after %s answers %d of %d defences", node.Move.UCI(), len(node.Children), moves)
+	}
+	for _, child := range node.Children {
+		checkProofTree(t, child, status)
+	}
+}
+
+func TestProveDisproof(t *testing.T) {
+	// Black to move is stalemated, so it cannot win
+	prover := &Prover{}
+	result := prover.Prove(context.Background(), parseFEN(t, "k7/2Q5/1K6/8/8/8/8/8 b - - 0 1"))
+	if result.Status != Disproven {
+		t.Errorf("stalemate: status %s, want disproven", result.Status)
+	}
+
+	// A budget too small to decide anything leaves the root unknown
+	prover = &Prover{MaxNodes: 10}
+	result = prover.Prove(context.Background(), parseFEN(t, components.StartFEN))
+	if result.Status != Unknown || result.Nodes < 10 {
+		t.Errorf("start position with 10 nodes: status %s after %d nodes, want unknown", result.Status, result.Nodes)
+	}
+}
+
+func uciLine(moves []components.Move) []string {
+	line := make([]string, len(moves))
+	for i, move := range moves {
+		line[i] = move.UCI()
+	}
+	return line
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"context"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+const (
+	// Captures that cannot lift the stand-pat score this close to alpha
+	// even after winning the piece are skipped (delta pruning)
+	deltaMargin = 200
+
+	// Hard limit on quiescence depth, which is otherwise bounded by material
+	maxPly = 2 * MaxDepth
+)
+
+// noHistory is shared by standalone quiescence searches, which only read the
+// ordering tables and so never need killers or history of their own
+var noHistory = &moveOrdering{}
+
+// Quiesce returns the score of board from the side to move once all captures
+// and promotions have been resolved. It is the leaf evaluation used by the
+// search and by minimax back-propagation over stored trees.
+func Quiesce(board components.ChessBoard) int {
+	e := &Engine{ctx: context.Background(), ordering: noHistory, variant: components.VariantOf(&board)}
+	return e.quiesce(&board, 0, -infinity, infinity)
+}
+
+// quiesce searches only captures and promotions until the position is quiet.
+// The side to move may stand pat on the static evaluation unless it is in
+// check, in which case every evasion is searched.
+func (e *Engine) quiesce(board *components.ChessBoard, ply, alpha, beta int) int {
+	e.nodes++
+	if e.nodes%checkInterval == 0 && e.outOfTime() {
+		e.stopped = true
+	}
+	if e.stopped {
+		return 0
+	}
+
+	if ply >= maxPly {
+		return board.EvaluateRelative()
+	}
+
+	if result := e.variant.Decided(board); result != components.Ongoing {
+		return resultScore(result, board, ply)
+	}
+
+	var children []*components.ChessBoard
+	standPat := -infinity
+	if e.variant.InCheck(board) {
+		children = components.LegalMoves(e.variant, board)
+		if result := e.variant.Result(board, children); result != components.Ongoing {
+			return resultScore(result, board, ply)
+		}
+	} else {
+		standPat = board.EvaluateRelative()
+		if standPat >= beta {
+			return beta
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+		children = components.LegalCaptures(e.variant, board)
+	}
+	// Captures are always ordered: unordered quiescence explodes in
+	// tactical positions
+	e.ordering.order(board, children, components.Move{}, ply)
+
+	for _, child := range children {
+		if standPat > -infinity && standPat+captureGain(board, child)+deltaMargin <= alpha {
+			continue
+		}
+
+		score := -e.quiesce(child, ply+1, -beta, -alpha)
+		if e.stopped {
+			return 0
+		}
+		if score >= beta {
+			return beta
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+	return alpha
+}
+
+// captureGain is the material won by the move leading from board to child
+func captureGain(board, child *components.ChessBoard) int {
+	move := child.LastMove
+	gain := 0
+	if captured := board.CapturedPiece(move); captured != nil {
+		gain += components.PieceValue(captured)
+	}
+	if move.Promotion != 0 {
+		gain += components.PieceValueOf(move.Promotion) - components.PieceValueOf('P')
+	}
+	return gain
+}
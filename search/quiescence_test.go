@@ -0,0 +1,26 @@
+package search
+
+import "testing"
+
+func TestQuiesceTakesHangingPiece(t *testing.T) {
+	// exd5 wins the queen for nothing
+	board := parseFEN(t, "4k3/8/8/3q4/4P3/8/8/4K3 w - - 0 1")
+	after, err := board.ApplyUCIMove("e4d5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Quiesce(board), -after.EvaluateRelative(); got != want {
+		t.Errorf("Quiesce %d, want %d after exd5", got, want)
+	}
+	if got := Quiesce(board); got < board.EvaluateRelative()+500 {
+		t.Errorf("Quiesce %d barely above the static %d", got, board.EvaluateRelative())
+	}
+}
+
+func TestQuiesceStandsPat(t *testing.T) {
+	// Qxd5 cxd5 loses the queen, so White keeps the static score
+	board := parseFEN(t, "4k3/8/2p5/3p4/8/8/8/3QK3 w - - 0 1")
+	if got, want := Quiesce(board), board.EvaluateRelative(); got != want {
+		t.Errorf("Quiesce %d, want the static %d", got, want)
+	}
+}
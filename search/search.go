@@ -0,0 +1,397 @@
+// Package search picks moves for a ChessBoard with an iterative deepening
+// negamax alpha-beta search.
+package search
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/tablebase"
+)
+
+const (
+	MaxDepth = 64
+	infinity = components.MateScore + 1
+
+	// Default transposition table size in megabytes
+	DefaultHashMB = 32
+
+	// How many nodes are searched between checks of the context
+	checkInterval = 1024
+)
+
+// Limits bounds a search. Cancelling the context stops it as well.
+type Limits struct {
+	Depth   int          // maximum iteration depth, 0 for MaxDepth
+	Time    *TimeManager // clock based limits, nil for none
+	MultiPV int          // number of best lines to find, 0 or 1 for just the best
+}
+
+// Line is one ranked root move with its score and principal variation
+type Line struct {
+	Score int
+	PV    []components.Move
+}
+
+// Info is reported after every completed iteration of iterative deepening
+type Info struct {
+	Depth   int
+	Score   int // centipawns from the side to move, see components.MateScore
+	Nodes   uint64
+	PV      []components.Move
+	Lines   []Line // all lines best first; Score and PV repeat the first
+	Elapsed time.Duration
+}
+
+// Result is the outcome of the deepest completed iteration
+type Result struct {
+	BestMove components.Move
+	Score    int
+	Depth    int
+	Nodes    uint64
+	PV       []components.Move
+	Lines    []Line
+}
+
+// Engine holds the state of a search. An Engine can be reused for several
+// searches but only runs one at a time.
+type Engine struct {
+	// OnIteration, if set, is called after every completed iteration
+	OnIteration func(Info)
+
+	// TT is kept between searches; replace it to share a table between engines
+	TT *TranspositionTable
+
+	// MoveOrdering enables MVV-LVA, killer and history ordering in the main
+	// search. Without it only the hash or principal variation move is
+	// searched first. Quiescence search always orders captures.
+	MoveOrdering bool
+
+	// Tablebases, if set, give exact scores for positions with few pieces at
+	// the root and inside the tree. They are only used in standard chess.
+	Tablebases *tablebase.Set
+
+	// Variant gives the rules to search under; nil uses the variant of the
+	// board being searched
+	Variant components.Variant
+
+	ctx      context.Context
+	variant  components.Variant
+	timer    *TimeManager
+	nodes    uint64
+	stopped  bool
+	prevPV   []components.Move
+	excluded []components.Move // root moves already taken by better lines
+	ordering *moveOrdering
+}
+
+func NewEngine() *Engine {
+	return &Engine{
+		TT:           NewTranspositionTable(DefaultHashMB),
+		MoveOrdering: true,
+		ordering:     &moveOrdering{},
+	}
+}
+
+// Search runs iterative deepening from board until limits.Depth is reached or
+// ctx is cancelled. The result always comes from the last iteration that
+// finished; if none did, the first legal move is returned. With
+// limits.MultiPV above one every iteration searches the root again for each
+// line, excluding the moves of the lines found before it.
+func (e *Engine) Search(ctx context.Context, board components.ChessBoard, limits Limits) Result {
+	maxDepth := limits.Depth
+	if maxDepth <= 0 || maxDepth > MaxDepth {
+		maxDepth = MaxDepth
+	}
+	multiPV := limits.MultiPV
+	if multiPV < 1 {
+		multiPV = 1
+	}
+	e.variant = e.Variant
+	if e.variant == nil {
+		e.variant = components.VariantOf(&board)
+	}
+	if legal := len(components.LegalMoves(e.variant, &board)); multiPV > legal {
+		multiPV = legal
+	}
+
+	e.ctx = ctx
+	e.timer = limits.Time
+	if e.timer != nil {
+		e.timer.Start()
+	}
+	e.nodes = 0
+	e.stopped = false
+	e.prevPV = nil
+	e.TT.NewSearch()
+	e.ordering.clear()
+	start := time.Now()
+
+	if result, ok := e.tablebaseRoot(&board, multiPV); ok {
+		if e.OnIteration != nil {
+			e.OnIteration(Info{
+				Depth:   result.Depth,
+				Score:   result.Score,
+				Nodes:   result.Nodes,
+				PV:      result.PV,
+				Lines:   result.Lines,
+				Elapsed: time.Since(start),
+			})
+		}
+		return result
+	}
+
+	var result Result
+	for depth := 1; depth <= maxDepth; depth++ {
+		lines := e.searchLines(&board, depth, multiPV, result.Lines)
+		if e.stopped {
+			break
+		}
+
+		var score int
+		var pv []components.Move
+		if len(lines) > 0 {
+			score, pv = lines[0].Score, lines[0].PV
+		} else {
+			// No legal moves, score the root itself
+			score, _ = e.negamax(&board, depth, 0, -infinity, infinity, false)
+		}
+		result = Result{Score: score, Depth: depth, Nodes: e.nodes, PV: pv, Lines: lines}
+		if len(pv) > 0 {
+			result.BestMove = pv[0]
+		}
+		if e.OnIteration != nil {
+			e.OnIteration(Info{
+				Depth:   depth,
+				Score:   score,
+				Nodes:   e.nodes,
+				PV:      pv,
+				Lines:   lines,
+				Elapsed: time.Since(start),
+			})
+		}
+
+		// No point searching deeper once a forced mate has been found
+		if multiPV <= 1 && (score > components.MateThreshold || score < -components.MateThreshold) || len(pv) == 0 {
+			break
+		}
+		if e.timer != nil && !e.timer.Continue(result.BestMove, score) {
+			break
+		}
+	}
+
+	result.Nodes = e.nodes
+	if result.BestMove.IsNull() {
+		if moves := components.LegalMoves(e.variant, &board); len(moves) > 0 {
+			result.BestMove = moves[0].LastMove
+		}
+	}
+	return result
+}
+
+// searchLines searches the root once per line, each time excluding the first
+// moves of the lines already found, and returns them best first. prevLines
+// from the last iteration supply the principal variation to follow.
+func (e *Engine) searchLines(board *components.ChessBoard, depth, multiPV int, prevLines []Line) []Line {
+	e.excluded = e.excluded[:0]
+	var lines []Line
+	for k := 0; k < multiPV; k++ {
+		e.prevPV = nil
+		if k < len(prevLines) {
+			e.prevPV = prevLines[k].PV
+		}
+
+		score, pv := e.negamax(board, depth, 0, -infinity, infinity, true)
+		if e.stopped || len(pv) == 0 {
+			break
+		}
+		lines = append(lines, Line{Score: score, PV: pv})
+		e.excluded = append(e.excluded, pv[0])
+	}
+
+	// A later line can outscore an earlier one when the search is unstable
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Score > lines[j].Score
+	})
+	return lines
+}
+
+// negamax returns the score of board from the side to move and the principal
+// variation below it. onPV is true while following the previous iteration's
+// principal variation, whose move is then searched first.
+func (e *Engine) negamax(board *components.ChessBoard, depth, ply, alpha, beta int, onPV bool) (int, []components.Move) {
+	if depth == 0 {
+		return e.quiesce(board, ply, alpha, beta), nil
+	}
+
+	e.nodes++
+	if e.nodes%checkInterval == 0 && e.outOfTime() {
+		e.stopped = true
+	}
+	if e.stopped {
+		return 0, nil
+	}
+
+	if ply > 0 && e.tablebases() != nil {
+		if v, ok := e.Tablebases.Probe(board); ok {
+			return v.Score(ply), nil
+		}
+	}
+
+	alphaOrig := alpha
+	hash := board.Hash()
+	var hashMove components.Move
+	if entry, ok := e.TT.Probe(hash); ok {
+		hashMove = entry.BestMove
+		if ply > 0 && entry.Depth >= depth {
+			score := fromTT(entry.Score, ply)
+			if entry.Bound == BoundExact ||
+				(entry.Bound == BoundLower && score >= beta) ||
+				(entry.Bound == BoundUpper && score <= alpha) {
+				var pv []components.Move
+				if !hashMove.IsNull() {
+					pv = []components.Move{hashMove}
+				}
+				return score, pv
+			}
+		}
+	}
+
+	children := components.LegalMoves(e.variant, board)
+	if result := e.variant.Result(board, children); result != components.Ongoing {
+		return resultScore(result, board, ply), nil
+	}
+
+	firstMove := hashMove
+	if onPV && ply < len(e.prevPV) {
+		firstMove = e.prevPV[ply]
+	} else {
+		onPV = false
+	}
+	if e.MoveOrdering {
+		e.ordering.order(board, children, firstMove, ply)
+	} else if !firstMove.IsNull() {
+		children = moveToFront(children, firstMove)
+	}
+
+	var pv []components.Move
+	for i, child := range children {
+		if ply == 0 && e.isExcluded(child.LastMove) {
+			continue
+		}
+		score, childPV := e.negamax(child, depth-1, ply+1, -beta, -alpha, onPV && i == 0)
+		score = -score
+		if e.stopped {
+			return 0, nil
+		}
+
+		if score > alpha {
+			alpha = score
+			pv = append([]components.Move{child.LastMove}, childPV...)
+			if alpha >= beta {
+				if e.MoveOrdering {
+					e.ordering.recordCutoff(board, child.LastMove, depth, ply)
+				}
+				break
+			}
+		}
+	}
+
+	entry := Entry{Key: hash, Depth: depth, Bound: BoundExact, Score: toTT(alpha, ply)}
+	if alpha <= alphaOrig {
+		entry.Bound = BoundUpper
+	} else if alpha >= beta {
+		entry.Bound = BoundLower
+	}
+	if len(pv) > 0 {
+		entry.BestMove = pv[0]
+	}
+	// With root moves excluded the root score is not the position's score
+	if ply > 0 || len(e.excluded) == 0 {
+		e.TT.Store(entry)
+	}
+	return alpha, pv
+}
+
+// tablebaseRoot ranks the root moves by their tablebase values when every
+// move leads to a position in the tables, which makes searching unnecessary
+func (e *Engine) tablebaseRoot(board *components.ChessBoard, multiPV int) (Result, bool) {
+	if e.tablebases() == nil {
+		return Result{}, false
+	}
+	children := components.LegalMoves(e.variant, board)
+	if len(children) == 0 {
+		return Result{}, false
+	}
+
+	lines := make([]Line, len(children))
+	for i, child := range children {
+		v, ok := e.Tablebases.Probe(child)
+		if !ok {
+			return Result{}, false
+		}
+		lines[i] = Line{Score: -v.Score(1), PV: []components.Move{child.LastMove}}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Score > lines[j].Score
+	})
+	lines = lines[:multiPV]
+
+	return Result{
+		BestMove: lines[0].PV[0],
+		Score:    lines[0].Score,
+		Depth:    1,
+		Nodes:    uint64(len(children)),
+		PV:       lines[0].PV,
+		Lines:    lines,
+	}, true
+}
+
+// tablebases returns the tables to probe, which only hold standard chess
+func (e *Engine) tablebases() *tablebase.Set {
+	if _, standard := e.variant.(components.Standard); !standard {
+		return nil
+	}
+	return e.Tablebases
+}
+
+// resultScore scores a finished game from the side to move in board, ply
+// plies from the root. Wins and losses count as mates so shorter wins score
+// higher.
+func resultScore(result components.Result, board *components.ChessBoard, ply int) int {
+	switch result.ForSide(board.NextTurn) {
+	case 1:
+		return components.MateScore - ply
+	case -1:
+		return -components.MateScore + ply
+	}
+	return 0
+}
+
+func (e *Engine) isExcluded(move components.Move) bool {
+	for _, excluded := range e.excluded {
+		if excluded == move {
+			return true
+		}
+	}
+	return false
+}
+
+// outOfTime reports whether the context was cancelled or the hard time limit
+// has passed
+func (e *Engine) outOfTime() bool {
+	return e.ctx.Err() != nil || (e.timer != nil && e.timer.HardExpired())
+}
+
+// moveToFront reorders children so the one reached by move is searched first
+func moveToFront(children []*components.ChessBoard, move components.Move) []*components.ChessBoard {
+	for i, child := range children {
+		if child.LastMove == move {
+			children[0], children[i] = children[i], children[0]
+			break
+		}
+	}
+	return children
+}
@@ -0,0 +1,98 @@
+package search
+
+import (
+	"context"
+	"testing"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+func parseFEN(t *testing.T, fen string) components.ChessBoard {
+	t.Helper()
+	board, err := components.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return board
+}
+
+func TestSearchFindsMate(t *testing.T) {
+	// Qh8# and Qc8# both mate
+	board := parseFEN(t, "k7/8/1K6/8/8/7Q/8/8 w - - 0 1")
+	result := NewEngine().Search(context.Background(), board, Limits{Depth: 4})
+	if result.Score != components.MateScore-1 {
+		t.Errorf("score %s, want mate in one", components.FormatScore(result.Score))
+	}
+	if move := result.BestMove.UCI(); move != "h3h8" && move != "h3c8" {
+		t.Errorf("best move %s, want h3h8 or h3c8", move)
+	}
+	// The search stops deepening once it has found the mate
+	if result.Depth != 1 {
+		t.Errorf("searched to depth %d after finding mate in one", result.Depth)
+	}
+}
+
+func TestSearchWinsMaterial(t *testing.T) {
+	// Nc7+ forks king and queen
+	board := parseFEN(t, "q3k3/8/8/1N6/8/8/8/4K3 w - - 0 1")
+	var depths []int
+	engine := NewEngine()
+	engine.OnIteration = func(info Info) { depths = append(depths, info.Depth) }
+	result := engine.Search(context.Background(), board, Limits{Depth: 3})
+	if result.BestMove.UCI() != "b5c7" {
+		t.Errorf("best move %s, want b5c7", result.BestMove.UCI())
+	}
+	if result.Score < 200 {
+		t.Errorf("score %d after winning the queen for a knight", result.Score)
+	}
+	if len(depths) != 3 || depths[2] != 3 || result.Depth != 3 {
+		t.Errorf("iterations %v, result depth %d; want 1 to 3", depths, result.Depth)
+	}
+}
+
+func TestSearchCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	board := parseFEN(t, components.StartFEN)
+	result := NewEngine().Search(ctx, board, Limits{})
+	if _, err := board.ApplyUCIMove(result.BestMove.UCI()); err != nil {
+		t.Errorf("cancelled search returned %s: %v", result.BestMove.UCI(), err)
+	}
+}
+
+func TestMultiPV(t *testing.T) {
+	board := parseFEN(t, perftPositions[4].fen)
+	var infos []Info
+	engine := NewEngine()
+	engine.OnIteration = func(info Info) { infos = append(infos, info) }
+	result := engine.Search(context.Background(), board, Limits{Depth: 3, MultiPV: 4})
+
+	if len(result.Lines) != 4 {
+		t.Fatalf("%d lines, want 4", len(result.Lines))
+	}
+	seen := make(map[components.Move]bool)
+	for i, line := range result.Lines {
+		if len(line.PV) == 0 {
+			t.Fatalf("line %d has no moves", i)
+		}
+		if seen[line.PV[0]] {
+			t.Errorf("line %d repeats %s", i, line.PV[0].UCI())
+		}
+		seen[line.PV[0]] = true
+		if i > 0 && line.Score > result.Lines[i-1].Score {
+			t.Errorf("line %d scores %d, above line %d's %d", i, line.Score, i-1, result.Lines[i-1].Score)
+		}
+	}
+	if result.BestMove != result.Lines[0].PV[0] || result.Score != result.Lines[0].Score {
+		t.Errorf("best move %s %d is not the first line", result.BestMove.UCI(), result.Score)
+	}
+	if last := infos[len(infos)-1]; len(last.Lines) != 4 {
+		t.Errorf("last iteration reported %d lines", len(last.Lines))
+	}
+
+	// Asking for more lines than moves gives one per move
+	board = parseFEN(t, "k7/8/8/8/8/8/8/7K w - - 0 1")
+	if lines := engine.Search(context.Background(), board, Limits{Depth: 2, MultiPV: 10}).Lines; len(lines) != 3 {
+		t.Errorf("%d lines with three legal moves", len(lines))
+	}
+}
@@ -0,0 +1,157 @@
+package search
+
+import (
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+const (
+	// Moves assumed to remain in the game when the clock gives no movestogo
+	defaultMovesToGo = 30
+
+	// Time kept back for move transmission and GUI lag
+	moveOverhead = 50 * time.Millisecond
+
+	// The hard limit allows this many times the per-move allocation
+	hardLimitFactor = 4
+
+	// Score drops, in centipawns, that earn extra thinking time
+	smallScoreDrop = 25
+	largeScoreDrop = 75
+)
+
+// TimeControl describes the clock for the side to move
+type TimeControl struct {
+	Remaining time.Duration // time left on the clock, 0 if the clock is not used
+	Increment time.Duration
+	MovesToGo int           // moves until the next time control, 0 for sudden death
+	MoveTime  time.Duration // fixed time per move, overrides the clock
+}
+
+// TimeManager decides when iterative deepening should stop. The soft limit
+// is checked between iterations and grows when the search is unstable; the
+// hard limit aborts a running iteration.
+type TimeManager struct {
+	// Clock returns the current time. It defaults to time.Now and can be
+	// replaced for deterministic tests.
+	Clock func() time.Time
+
+	soft time.Duration
+	hard time.Duration
+
+	start         time.Time
+	lastIteration time.Duration
+	iterationEnd  time.Time
+	prevBest      components.Move
+	prevScore     int
+	iterations    int
+}
+
+// NewTimeManager allocates the soft and hard limit for one move
+func NewTimeManager(tc TimeControl) *TimeManager {
+	tm := &TimeManager{Clock: time.Now}
+	if tc.MoveTime > 0 {
+		tm.soft = tc.MoveTime
+		tm.hard = tc.MoveTime
+		return tm
+	}
+
+	movesToGo := tc.MovesToGo
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+	available := tc.Remaining - moveOverhead
+	if available < time.Millisecond {
+		available = time.Millisecond
+	}
+
+	tm.soft = tc.Remaining/time.Duration(movesToGo) + tc.Increment*3/4
+	tm.hard = tm.soft * hardLimitFactor
+	if limit := available / 2; tm.hard > limit && limit >= tm.soft {
+		tm.hard = limit
+	}
+	if tm.hard > available {
+		tm.hard = available
+	}
+	if tm.soft > tm.hard {
+		tm.soft = tm.hard
+	}
+	return tm
+}
+
+// Soft returns the allocated time for a stable search
+func (tm *TimeManager) Soft() time.Duration {
+	return tm.soft
+}
+
+// Hard returns the time after which a running iteration is abandoned
+func (tm *TimeManager) Hard() time.Duration {
+	return tm.hard
+}
+
+// Start marks the beginning of the search
+func (tm *TimeManager) Start() {
+	tm.start = tm.Clock()
+	tm.iterationEnd = tm.start
+	tm.lastIteration = 0
+	tm.iterations = 0
+}
+
+// Elapsed returns the time since Start
+func (tm *TimeManager) Elapsed() time.Duration {
+	return tm.Clock().Sub(tm.start)
+}
+
+// HardExpired reports whether the search must stop immediately
+func (tm *TimeManager) HardExpired() bool {
+	return tm.Elapsed() >= tm.hard
+}
+
+// Continue is called after every completed iteration with its best move and
+// score, and reports whether another iteration should be started. It stops
+// once the soft limit, extended when the best move changed or the score
+// dropped, is used up, or when the next iteration is predicted to run past
+// the hard limit.
+func (tm *TimeManager) Continue(best components.Move, score int) bool {
+	now := tm.Clock()
+	iteration := now.Sub(tm.iterationEnd)
+	elapsed := now.Sub(tm.start)
+
+	factor := 1.0
+	if tm.iterations > 0 {
+		if best != tm.prevBest {
+			factor += 0.5
+		}
+		if drop := tm.prevScore - score; drop >= largeScoreDrop {
+			factor += 1.0
+		} else if drop >= smallScoreDrop {
+			factor += 0.5
+		}
+	}
+
+	// Each iteration takes about as much longer than the last as the last
+	// did than the one before
+	growth := 2.0
+	if tm.lastIteration > 0 && iteration > 0 {
+		growth = float64(iteration) / float64(tm.lastIteration)
+		if growth < 1.5 {
+			growth = 1.5
+		} else if growth > 6 {
+			growth = 6
+		}
+	}
+	predicted := time.Duration(float64(iteration) * growth)
+
+	tm.prevBest = best
+	tm.prevScore = score
+	tm.lastIteration = iteration
+	tm.iterationEnd = now
+	tm.iterations++
+
+	soft := time.Duration(float64(tm.soft) * factor)
+	if soft > tm.hard {
+		soft = tm.hard
+	}
+	return elapsed < soft && elapsed+predicted < tm.hard
+}
@@ -0,0 +1,143 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// fakeClock is a TimeManager clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// at moves the clock to d after the epoch
+func (c *fakeClock) at(d time.Duration) {
+	c.now = time.Unix(0, 0).Add(d)
+}
+
+func startedManager(tc TimeControl) (*TimeManager, *fakeClock) {
+	clock := &fakeClock{}
+	clock.at(0)
+	tm := NewTimeManager(tc)
+	tm.Clock = clock.Now
+	tm.Start()
+	return tm, clock
+}
+
+var (
+	moveA = components.Move{From: components.Coordinates{X: 4, Y: 1}, To: components.Coordinates{X: 4, Y: 3}}
+	moveB = components.Move{From: components.Coordinates{X: 6, Y: 0}, To: components.Coordinates{X: 5, Y: 2}}
+)
+
+func TestTimeLimits(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		tc         TimeControl
+		soft, hard time.Duration
+	}{
+		{
+			name: "movestogo",
+			tc:   TimeControl{Remaining: 60 * time.Second, Increment: time.Second, MovesToGo: 20},
+			soft: 3750 * time.Millisecond, // 60s/20 + 3/4 of the increment
+			hard: 15 * time.Second,        // four times the soft limit
+		},
+		{
+			name: "sudden death",
+			tc:   TimeControl{Remaining: 30 * time.Second},
+			soft: time.Second, // 30s over the default 30 moves
+			hard: 4 * time.Second,
+		},
+		{
+			name: "hard limit capped at half the clock",
+			tc:   TimeControl{Remaining: 10 * time.Second, MovesToGo: 4},
+			soft: 2500 * time.Millisecond,
+			hard: (10*time.Second - moveOverhead) / 2,
+		},
+		{
+			name: "both limits capped at the clock",
+			tc:   TimeControl{Remaining: 2050 * time.Millisecond, Increment: time.Second, MovesToGo: 1},
+			soft: 2 * time.Second, // all of the clock but the overhead
+			hard: 2 * time.Second,
+		},
+		{
+			name: "nearly flagged",
+			tc:   TimeControl{Remaining: 200 * time.Millisecond, Increment: 100 * time.Millisecond},
+			soft: 200*time.Millisecond/30 + 75*time.Millisecond,
+			hard: 150 * time.Millisecond,
+		},
+		{
+			name: "movetime",
+			tc:   TimeControl{Remaining: time.Minute, MoveTime: 500 * time.Millisecond},
+			soft: 500 * time.Millisecond,
+			hard: 500 * time.Millisecond,
+		},
+	} {
+		tm := NewTimeManager(test.tc)
+		if tm.Soft() != test.soft || tm.Hard() != test.hard {
+			t.Errorf("%s: soft %s hard %s, want %s and %s", test.name, tm.Soft(), tm.Hard(), test.soft, test.hard)
+		}
+	}
+}
+
+func TestHardExpired(t *testing.T) {
+	tm, clock := startedManager(TimeControl{MoveTime: 500 * time.Millisecond})
+	clock.at(499 * time.Millisecond)
+	if tm.HardExpired() {
+		t.Error("expired before the hard limit")
+	}
+	clock.at(500 * time.Millisecond)
+	if !tm.HardExpired() {
+		t.Error("not expired at the hard limit")
+	}
+}
+
+// TestContinueExtension plays two iterations against a 1s soft and 4s hard
+// limit, the second ending at second after the start, and checks whether
+// the change between them earns another iteration
+func TestContinueExtension(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		second time.Duration
+		best   components.Move
+		score  int
+		want   bool
+	}{
+		{"stable", 1100 * time.Millisecond, moveA, 0, false},
+		{"best move changed", 1100 * time.Millisecond, moveB, 0, true},
+		{"best move changed, past extension", 1600 * time.Millisecond, moveB, 0, false},
+		{"small score drop", 1100 * time.Millisecond, moveA, -30, true},
+		{"small score drop, past extension", 1600 * time.Millisecond, moveA, -30, false},
+		{"large score drop", 1600 * time.Millisecond, moveA, -80, true},
+	} {
+		tm, clock := startedManager(TimeControl{Remaining: 30 * time.Second})
+		clock.at(800 * time.Millisecond)
+		if !tm.Continue(moveA, 0) {
+			t.Fatalf("%s: stopped after the first iteration", test.name)
+		}
+		clock.at(test.second)
+		if got := tm.Continue(test.best, test.score); got != test.want {
+			t.Errorf("%s: Continue %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestContinuePredictsHardLimit(t *testing.T) {
+	tm, clock := startedManager(TimeControl{Remaining: 30 * time.Second})
+	clock.at(100 * time.Millisecond)
+	if !tm.Continue(moveA, 0) {
+		t.Fatal("stopped after the first iteration")
+	}
+
+	// The iteration took six times the last, so the next is predicted to
+	// take 3.6s and end past the 4s hard limit, well inside the soft limit
+	clock.at(700 * time.Millisecond)
+	if tm.Continue(moveA, 0) {
+		t.Error("continued into an iteration predicted to pass the hard limit")
+	}
+}
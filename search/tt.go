@@ -0,0 +1,147 @@
+package search
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// Bound says how a stored score relates to the true score of the position
+type Bound uint8
+
+const (
+	BoundNone  Bound = iota
+	BoundExact       // score is exact
+	BoundLower       // search failed high, true score >= score
+	BoundUpper       // search failed low, true score <= score
+)
+
+// Entry is a single transposition table slot
+type Entry struct {
+	Key      uint64
+	Depth    int
+	Bound    Bound
+	Score    int
+	BestMove components.Move
+	age      uint8
+}
+
+const (
+	bucketSize = 4
+	stripes    = 1024
+)
+
+type bucket [bucketSize]Entry
+
+// TranspositionTable is a fixed-size hash table of search results keyed by
+// position hash. Buckets are guarded by a fixed set of striped locks, so the
+// table can be shared by goroutines searching in parallel.
+type TranspositionTable struct {
+	buckets []bucket
+	locks   [stripes]sync.Mutex
+	mask    uint64
+	age     atomic.Uint32
+}
+
+// NewTranspositionTable allocates a table of roughly sizeMB megabytes. The
+// bucket count is rounded down to a power of two.
+func NewTranspositionTable(sizeMB int) *TranspositionTable {
+	count := uint64(1)
+	target := uint64(sizeMB) * 1024 * 1024 / uint64(bucketSize*unsafe.Sizeof(Entry{}))
+	for count*2 <= target {
+		count *= 2
+	}
+	return &TranspositionTable{
+		buckets: make([]bucket, count),
+		mask:    count - 1,
+	}
+}
+
+// NewSearch ages the table so entries from earlier searches are replaced first
+func (tt *TranspositionTable) NewSearch() {
+	tt.age.Add(1)
+}
+
+// Clear empties the table
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.buckets {
+		lock := &tt.locks[uint64(i)%stripes]
+		lock.Lock()
+		tt.buckets[i] = bucket{}
+		lock.Unlock()
+	}
+	tt.age.Store(0)
+}
+
+// Probe returns the entry stored for key, if any
+func (tt *TranspositionTable) Probe(key uint64) (Entry, bool) {
+	index := key & tt.mask
+	lock := &tt.locks[index%stripes]
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, entry := range tt.buckets[index] {
+		if entry.Bound != BoundNone && entry.Key == key {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Store saves an entry. An existing entry for the same position is always
+// overwritten; otherwise the least valuable slot in the bucket is replaced,
+// where entries from older searches count as shallower than they are.
+func (tt *TranspositionTable) Store(entry Entry) {
+	index := entry.Key & tt.mask
+	lock := &tt.locks[index%stripes]
+	lock.Lock()
+	defer lock.Unlock()
+
+	age := uint8(tt.age.Load())
+	entry.age = age
+	slots := &tt.buckets[index]
+	replace := 0
+	lowest := 0
+	for i := range slots {
+		slot := &slots[i]
+		if slot.Bound == BoundNone || slot.Key == entry.Key {
+			// Keep the old best move if the new search did not find one
+			if slot.Key == entry.Key && entry.BestMove.IsNull() {
+				entry.BestMove = slot.BestMove
+			}
+			*slot = entry
+			return
+		}
+		worth := slot.Depth - 8*int(age-slot.age)
+		if i == 0 || worth < lowest {
+			replace = i
+			lowest = worth
+		}
+	}
+	slots[replace] = entry
+}
+
+// toTT converts a score relative to the root into one relative to the node at
+// ply, so mate scores stay correct when the entry is hit at a different ply
+func toTT(score, ply int) int {
+	if score > components.MateThreshold {
+		return score + ply
+	}
+	if score < -components.MateThreshold {
+		return score - ply
+	}
+	return score
+}
+
+// fromTT reverses toTT for a probe made at ply
+func fromTT(score, ply int) int {
+	if score > components.MateThreshold {
+		return score - ply
+	}
+	if score < -components.MateThreshold {
+		return score + ply
+	}
+	return score
+}
@@ -0,0 +1,569 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// PositionStore keeps an explored game graph: every position stored once,
+// the moves between them as parent to child edges, a frontier of positions
+// waiting to be expanded, and what the analysis commands have learned about
+// each position. Exploration and the browser only go through this interface.
+type PositionStore interface {
+	// PutPositions stores boards and returns their IDs in the same order. A
+	// board identical to a stored one gets the stored ID.
+	PutPositions(boards []*components.ChessBoard) ([]int, error)
+
+	// Position returns a stored board
+	Position(id int) (*components.ChessBoard, error)
+
+	// AddEdge records a move from one position to another, unlabeled if
+	// label is the zero MoveLabel. Adding an edge that is already stored only
+	// fills in a missing label.
+	AddEdge(parentID, childID int, label MoveLabel) error
+
+	// Children returns the positions a position has edges to, the ones MCTS
+	// visited most first
+	Children(parentID int) ([]int, error)
+
+	// Moves returns the labels of the edges from a position by child ID.
+	// Edges stored before labels existed are left out until the label
+	// command fills them in.
+	Moves(parentID int) (map[int]MoveLabel, error)
+
+	// Parents returns the positions with an edge to a position
+	Parents(childID int) ([]int, error)
+
+	// Enqueue adds positions to the frontier at a depth, and Dequeue removes
+	// and returns up to limit of them in the order they were added
+	Enqueue(depth int, ids []int) error
+	Dequeue(depth, limit int) ([]int, error)
+
+	// Pending counts the positions waiting in the frontier at a depth
+	Pending(depth int) (int, error)
+
+	// Metadata returns the analysis results stored for a position, and
+	// SetMetadata stores the fields of meta that are set, leaving the others
+	// as they are
+	Metadata(id int) (NodeMetadata, error)
+	SetMetadata(id int, meta NodeMetadata) error
+
+	// EdgeStats returns the MCTS statistics of the edges MCTS has expanded
+	// from a position, by child ID
+	EdgeStats(parentID int) ([]EdgeStat, error)
+
+	// SetPrior marks an edge as expanded by MCTS with the prior probability
+	// of its move, and AddVisit adds one visit scored value to it
+	SetPrior(parentID, childID int, prior float64) error
+	AddVisit(parentID, childID int, value float64) error
+
+	// Size returns the number of bytes the store takes up
+	Size() (int64, error)
+}
+
+// NodeMetadata is what the analysis commands store about a position. Nil
+// fields have not been computed.
+type NodeMetadata struct {
+	MinimaxValue *int    // negamax value from the minimax command
+	BestChild    *int    // child the minimax value comes from
+	ProofStatus  *string // outcome of the last prove through the node
+}
+
+// EdgeStat holds the MCTS statistics of one parent to child move. Values are
+// from the point of view of the side to move in the parent, in [-1, 1].
+type EdgeStat struct {
+	ChildID  int
+	Visits   int
+	ValueSum float64
+	Prior    float64
+}
+
+func (edge EdgeStat) MeanValue() float64 {
+	if edge.Visits == 0 {
+		return 0
+	}
+	return edge.ValueSum / float64(edge.Visits)
+}
+
+// MoveLabel is the move an edge stands for, as played on the parent
+type MoveLabel struct {
+	Move  components.Move
+	Flags components.MoveFlags
+	SAN   string // empty for a pseudo-legal move that is not legal
+}
+
+// newMoveLabel labels move on parent, with sans from parent.SANs()
+func newMoveLabel(parent *components.ChessBoard, sans map[components.Move]string, move components.Move) MoveLabel {
+	return MoveLabel{Move: move, Flags: parent.Flags(move), SAN: sans[move]}
+}
+
+// String returns the SAN of the move, or its UCI text if it has none
+func (label MoveLabel) String() string {
+	if label.SAN != "" {
+		return label.SAN
+	}
+	return label.Move.UCI()
+}
+
+// columns returns the values of the node_relations move columns: from, to,
+// promotion, flags, SAN and UCI. From is NULL for a drop, promotion for
+// anything but a promotion and SAN for a move that is not legal. All of them
+// are NULL for the zero label of an unlabeled edge.
+func (label MoveLabel) columns() []interface{} {
+	if label == (MoveLabel{}) {
+		return []interface{}{nil, nil, nil, nil, nil, nil}
+	}
+	move := label.Move
+	from := sql.NullString{String: components.SquareName(move.From), Valid: move.Drop == 0}
+	promotion := sql.NullString{String: string(move.Promotion), Valid: move.Promotion != 0}
+	san := sql.NullString{String: label.SAN, Valid: label.SAN != ""}
+	return []interface{}{from, components.SquareName(move.To), promotion, int(label.Flags), san, move.UCI()}
+}
+
+// labelFromColumns is the inverse of MoveLabel.columns
+func labelFromColumns(from sql.NullString, to string, promotion sql.NullString, flags int, san sql.NullString, uci string) (MoveLabel, error) {
+	label := MoveLabel{Flags: components.MoveFlags(flags), SAN: san.String}
+	var err error
+	if label.Move.To, err = components.ParseSquare(to); err != nil {
+		return label, err
+	}
+	if from.Valid {
+		if label.Move.From, err = components.ParseSquare(from.String); err != nil {
+			return label, err
+		}
+	} else {
+		label.Move.Drop = uci[0]
+	}
+	if promotion.Valid {
+		label.Move.Promotion = promotion.String[0]
+	}
+	return label, nil
+}
+
+// moveColumns lists the node_relations move columns in the order of
+// MoveLabel.columns, and moveColumnsUpdate copies them from a conflicting
+// insert
+const (
+	moveColumns       = "move_from, move_to, move_promotion, move_flags, move_san, move_uci"
+	moveColumnsUpdate = `
+		move_from = excluded.move_from, move_to = excluded.move_to,
+		move_promotion = excluded.move_promotion, move_flags = excluded.move_flags,
+		move_san = excluded.move_san, move_uci = excluded.move_uci`
+)
+
+// SQLiteStore is the PositionStore kept in chess.db
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore wraps a database whose schema has been created with
+// createSchema
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+func (s *SQLiteStore) PutPositions(boards []*components.ChessBoard) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback() // Will be ignored if transaction is committed
+
+	ids := make([]int, len(boards))
+	for i, board := range boards {
+		id, err := findOrStoreState(tx, board)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = int(id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return ids, nil
+}
+
+func (s *SQLiteStore) Position(id int) (*components.ChessBoard, error) {
+	var data string
+	err := s.db.QueryRow("SELECT state FROM board_states WHERE id = ?", id).Scan(&data)
+	if err != nil {
+		return nil, fmt.Errorf("error querying state %d: %v", id, err)
+	}
+
+	var board components.ChessBoard
+	if err := json.Unmarshal([]byte(data), &board); err != nil {
+		return nil, fmt.Errorf("error unmarshaling state %d: %v", id, err)
+	}
+	return &board, nil
+}
+
+func (s *SQLiteStore) AddEdge(parentID, childID int, label MoveLabel) error {
+	_, err := s.db.Exec(`
+		INSERT INTO node_relations (parent_id, child_id, `+moveColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(parent_id, child_id) DO UPDATE SET `+moveColumnsUpdate+`
+		WHERE move_uci IS NULL`,
+		append([]interface{}{parentID, childID}, label.columns()...)...)
+	if err != nil {
+		return fmt.Errorf("failed to store node relation: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Moves(parentID int) (map[int]MoveLabel, error) {
+	rows, err := s.db.Query(`
+		SELECT child_id, `+moveColumns+`
+		FROM node_relations
+		WHERE parent_id = ? AND move_uci IS NOT NULL
+	`, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying moves: %v", err)
+	}
+	defer rows.Close()
+
+	moves := make(map[int]MoveLabel)
+	for rows.Next() {
+		var childID, flags int
+		var from, promotion, san sql.NullString
+		var to, uci string
+		if err := rows.Scan(&childID, &from, &to, &promotion, &flags, &san, &uci); err != nil {
+			return nil, fmt.Errorf("error scanning move: %v", err)
+		}
+		label, err := labelFromColumns(from, to, promotion, flags, san, uci)
+		if err != nil {
+			return nil, fmt.Errorf("error reading move %d -> %d: %v", parentID, childID, err)
+		}
+		moves[childID] = label
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over moves: %v", err)
+	}
+	return moves, nil
+}
+
+func (s *SQLiteStore) Children(parentID int) ([]int, error) {
+	return s.queryIDs(`
+		SELECT child_id
+		FROM node_relations
+		WHERE parent_id = ?
+		ORDER BY visits DESC, child_id
+	`, parentID)
+}
+
+func (s *SQLiteStore) Parents(childID int) ([]int, error) {
+	return s.queryIDs(`
+		SELECT parent_id
+		FROM node_relations
+		WHERE child_id = ?
+		ORDER BY parent_id
+	`, childID)
+}
+
+func (s *SQLiteStore) Enqueue(depth int, ids []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting transaction for excess queue: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare("INSERT INTO excess_queue (state_id, depth) VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("error preparing excess queue statement: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.Exec(id, depth); err != nil {
+			return fmt.Errorf("error storing excess state %d: %v", id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing excess queue transaction: %v", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Dequeue(depth, limit int) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for excess queue: %v", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, state_id FROM excess_queue WHERE depth = ? ORDER BY id LIMIT ?", depth, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error querying excess queue: %v", err)
+	}
+	var ids []int
+	lastRow := 0
+	for rows.Next() {
+		var stateID int
+		if err := rows.Scan(&lastRow, &stateID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning excess queue row: %v", err)
+		}
+		ids = append(ids, stateID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over excess queue: %v", err)
+	}
+
+	// Rows are taken in ID order, so everything up to the last one read is
+	// what was dequeued
+	if _, err := tx.Exec("DELETE FROM excess_queue WHERE depth = ? AND id <= ?", depth, lastRow); err != nil {
+		return nil, fmt.Errorf("error deleting processed excess states: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error committing excess queue transaction: %v", err)
+	}
+	return ids, nil
+}
+
+func (s *SQLiteStore) Pending(depth int) (int, error) {
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM excess_queue WHERE depth = ?", depth).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting excess states: %v", err)
+	}
+	return count, nil
+}
+
+func (s *SQLiteStore) Metadata(id int) (NodeMetadata, error) {
+	var value, bestChild sql.NullInt64
+	var proofStatus sql.NullString
+	err := s.db.QueryRow("SELECT minimax_value, best_child_id, proof_status FROM board_states WHERE id = ?", id).
+		Scan(&value, &bestChild, &proofStatus)
+	if err != nil {
+		return NodeMetadata{}, fmt.Errorf("error querying node metadata: %v", err)
+	}
+
+	var meta NodeMetadata
+	if value.Valid {
+		v := int(value.Int64)
+		meta.MinimaxValue = &v
+	}
+	if bestChild.Valid {
+		child := int(bestChild.Int64)
+		meta.BestChild = &child
+	}
+	if proofStatus.Valid {
+		meta.ProofStatus = &proofStatus.String
+	}
+	return meta, nil
+}
+
+func (s *SQLiteStore) SetMetadata(id int, meta NodeMetadata) error {
+	result, err := s.db.Exec(`
+		UPDATE board_states
+		SET minimax_value = COALESCE(?, minimax_value),
+			best_child_id = COALESCE(?, best_child_id),
+			proof_status = COALESCE(?, proof_status)
+		WHERE id = ?`,
+		meta.MinimaxValue, meta.BestChild, meta.ProofStatus, id)
+	if err != nil {
+		return fmt.Errorf("error storing node metadata: %v", err)
+	}
+	return expectRow(result, "no state with ID %d", id)
+}
+
+func (s *SQLiteStore) EdgeStats(parentID int) ([]EdgeStat, error) {
+	rows, err := s.db.Query(`
+		SELECT child_id, visits, value_sum, prior
+		FROM node_relations
+		WHERE parent_id = ? AND prior IS NOT NULL
+		ORDER BY child_id
+	`, parentID)
+	if err != nil {
+		return nil, fmt.Errorf("error querying edges: %v", err)
+	}
+	defer rows.Close()
+
+	var edges []EdgeStat
+	for rows.Next() {
+		var edge EdgeStat
+		if err := rows.Scan(&edge.ChildID, &edge.Visits, &edge.ValueSum, &edge.Prior); err != nil {
+			return nil, fmt.Errorf("error scanning edge: %v", err)
+		}
+		edges = append(edges, edge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over edges: %v", err)
+	}
+	return edges, nil
+}
+
+func (s *SQLiteStore) SetPrior(parentID, childID int, prior float64) error {
+	result, err := s.db.Exec("UPDATE node_relations SET prior = ? WHERE parent_id = ? AND child_id = ?", prior, parentID, childID)
+	if err != nil {
+		return fmt.Errorf("error storing prior of edge %d -> %d: %v", parentID, childID, err)
+	}
+	return expectRow(result, "no edge %d -> %d", parentID, childID)
+}
+
+func (s *SQLiteStore) AddVisit(parentID, childID int, value float64) error {
+	result, err := s.db.Exec(`
+		UPDATE node_relations
+		SET visits = visits + 1, value_sum = value_sum + ?
+		WHERE parent_id = ? AND child_id = ?`,
+		value, parentID, childID)
+	if err != nil {
+		return fmt.Errorf("error updating edge %d -> %d: %v", parentID, childID, err)
+	}
+	return expectRow(result, "no edge %d -> %d", parentID, childID)
+}
+
+// expectRow returns the formatted error when an update changed no row
+func expectRow(result sql.Result, format string, args ...interface{}) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error counting updated rows: %v", err)
+	}
+	if n == 0 {
+		return fmt.Errorf(format, args...)
+	}
+	return nil
+}
+
+// Size counts the database file and its write-ahead log, after a checkpoint
+// so the log is as small as it gets
+func (s *SQLiteStore) Size() (int64, error) {
+	if _, err := s.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return 0, fmt.Errorf("checkpoint error: %v", err)
+	}
+
+	var size, walSize int64
+	err := s.db.QueryRow("SELECT page_count * page_size FROM pragma_page_count, pragma_page_size").Scan(&size)
+	if err != nil {
+		return 0, err
+	}
+
+	// If the WAL size cannot be read, just use the main DB size
+	if err := s.db.QueryRow("SELECT SUM(s) FROM (SELECT size as s FROM pragma_wal_checkpoint)").Scan(&walSize); err != nil {
+		walSize = 0
+	}
+	return size + walSize, nil
+}
+
+func (s *SQLiteStore) queryIDs(query string, args ...interface{}) ([]int, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying node relations: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning node ID: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %v", err)
+	}
+	return ids, nil
+}
+
+// findOrStoreState returns the ID of board in board_states within tx,
+// inserting it if the exact state is not stored yet. States are found through
+// the unique index on their position hash and a slot number. The stored
+// payload is compared too, and a different state with the same hash, such as
+// the same position under another variant, takes the next slot.
+func findOrStoreState(tx *sql.Tx, board *components.ChessBoard) (int64, error) {
+	data, err := json.Marshal(board)
+	if err != nil {
+		return 0, fmt.Errorf("error marshaling state: %v", err)
+	}
+
+	hash := int64(board.Hash())
+	for slot := 0; ; slot++ {
+		// The no-op update makes RETURNING report the row already in the
+		// slot, which DO NOTHING would not
+		var id int64
+		var stored string
+		err := tx.QueryRow(`
+			INSERT INTO board_states (state, state_hash, hash_slot)
+			VALUES (?, ?, ?)
+			ON CONFLICT(state_hash, hash_slot) DO UPDATE SET state_hash = excluded.state_hash
+			RETURNING id, state`,
+			string(data), hash, slot).Scan(&id, &stored)
+		if err != nil {
+			return 0, fmt.Errorf("error storing state: %v", err)
+		}
+		if stored == string(data) {
+			return id, nil
+		}
+	}
+}
+
+// backfillStateHashes fills in the hash and slot of states stored before
+// those columns existed. Copies of a state stored twice by racing workers
+// keep their own rows in separate slots, as edges refer to both.
+func backfillStateHashes(db *sql.DB) error {
+	var missing int
+	if err := db.QueryRow("SELECT COUNT(*) FROM board_states WHERE state_hash IS NULL").Scan(&missing); err != nil {
+		return fmt.Errorf("error counting unhashed states: %v", err)
+	}
+	if missing == 0 {
+		return nil
+	}
+	fmt.Printf("Hashing %d stored states\n", missing)
+
+	for {
+		rows, err := db.Query("SELECT id, state FROM board_states WHERE state_hash IS NULL ORDER BY id LIMIT 1000")
+		if err != nil {
+			return fmt.Errorf("error querying unhashed states: %v", err)
+		}
+		var ids []int
+		var states []string
+		for rows.Next() {
+			var id int
+			var data string
+			if err := rows.Scan(&id, &data); err != nil {
+				rows.Close()
+				return fmt.Errorf("error scanning state: %v", err)
+			}
+			ids = append(ids, id)
+			states = append(states, data)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating over states: %v", err)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %v", err)
+		}
+		for i, id := range ids {
+			var board components.ChessBoard
+			if err := json.Unmarshal([]byte(states[i]), &board); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("error unmarshaling state %d: %v", id, err)
+			}
+			hash := int64(board.Hash())
+			_, err := tx.Exec(`
+				UPDATE board_states
+				SET state_hash = ?, hash_slot = (
+					SELECT COALESCE(MAX(hash_slot) + 1, 0) FROM board_states WHERE state_hash = ?
+				)
+				WHERE id = ?`,
+				hash, hash, id)
+			if err != nil {
+				tx.Rollback()
+				return fmt.Errorf("error storing hash of state %d: %v", id, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %v", err)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// storeBackends opens an empty store of every kind
+var storeBackends = []struct {
+	name string
+	open func(t *testing.T) PositionStore
+}{
+	{"memory", func(t *testing.T) PositionStore {
+		return NewMemoryStore()
+	}},
+	{"sqlite", func(t *testing.T) PositionStore {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every connection to :memory: is a database of its own
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { db.Close() })
+		if err := createSchema(db); err != nil {
+			t.Fatal(err)
+		}
+		return NewSQLiteStore(db)
+	}},
+}
+
+// storeTests run against every backend, each with a store holding the start
+// position and its first three legal children
+var storeTests = []struct {
+	name string
+	run  func(t *testing.T, store PositionStore, start *components.ChessBoard, children []*components.ChessBoard, ids []int)
+}{
+	{"deduplicates positions", testStoreDedup},
+	{"edges", testStoreEdges},
+	{"labels filled in", testStoreLabelFill},
+	{"frontier", testStoreFrontier},
+	{"metadata", testStoreMetadata},
+	{"edge stats", testStoreEdgeStats},
+}
+
+func TestPositionStores(t *testing.T) {
+	for _, backend := range storeBackends {
+		for _, test := range storeTests {
+			t.Run(backend.name+"/"+test.name, func(t *testing.T) {
+				store := backend.open(t)
+				start := components.Standard{}.StartPosition()
+				children := components.LegalMoves(components.Standard{}, &start)[:3]
+				ids, err := store.PutPositions(append([]*components.ChessBoard{&start}, children...))
+				if err != nil {
+					t.Fatal(err)
+				}
+				test.run(t, store, &start, children, ids)
+			})
+		}
+	}
+}
+
+func testStoreDedup(t *testing.T, store PositionStore, start *components.ChessBoard, children []*components.ChessBoard, ids []int) {
+	again := *start
+	again.LastMove = components.Move{To: components.Coordinates{X: 1}} // not part of the position
+	got, err := store.PutPositions([]*components.ChessBoard{children[1], &again, start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{ids[2], ids[0], ids[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IDs of stored positions %v, want %v", got, want)
+	}
+	if len(map[int]bool{ids[0]: true, ids[1]: true, ids[2]: true, ids[3]: true}) != 4 {
+		t.Errorf("different positions share IDs %v", ids)
+	}
+
+	board, err := store.Position(ids[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.ToFEN() != children[0].ToFEN() {
+		t.Errorf("position %d is %s, want %s", ids[1], board.ToFEN(), children[0].ToFEN())
+	}
+	if _, err := store.Position(ids[3] + 100); err == nil {
+		t.Error("no error for a missing position")
+	}
+}
+
+func testStoreEdges(t *testing.T, store PositionStore, start *components.ChessBoard, children []*components.ChessBoard, ids []int) {
+	sans := start.SANs()
+	labels := make([]MoveLabel, len(children))
+	for i := len(children) - 1; i >= 0; i-- {
+		labels[i] = newMoveLabel(start, sans, children[i].LastMove)
+		if err := store.AddEdge(ids[0], ids[i+1], labels[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Adding an edge again changes nothing
+	if err := store.AddEdge(ids[0], ids[1], labels[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Children(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ids[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Children %v, want %v", got, want)
+	}
+	got, err = store.Parents(ids[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{ids[0]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Parents %v, want %v", got, want)
+	}
+
+	moves, err := store.Moves(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, label := range labels {
+		if moves[ids[i+1]] != label {
+			t.Errorf("move to %d is %+v, want %+v", ids[i+1], moves[ids[i+1]], label)
+		}
+	}
+	if leaf, err := store.Children(ids[1]); err != nil || len(leaf) != 0 {
+		t.Errorf("leaf has children %v, %v", leaf, err)
+	}
+}
+
+func testStoreLabelFill(t *testing.T, store PositionStore, start *components.ChessBoard, children []*components.ChessBoard, ids []int) {
+	if err := store.AddEdge(ids[0], ids[1], MoveLabel{}); err != nil {
+		t.Fatal(err)
+	}
+	moves, err := store.Moves(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 0 {
+		t.Errorf("unlabeled edge has moves %v", moves)
+	}
+
+	label := newMoveLabel(start, start.SANs(), children[0].LastMove)
+	if err := store.AddEdge(ids[0], ids[1], label); err != nil {
+		t.Fatal(err)
+	}
+	// A label once stored is kept
+	other := newMoveLabel(start, start.SANs(), children[1].LastMove)
+	if err := store.AddEdge(ids[0], ids[1], other); err != nil {
+		t.Fatal(err)
+	}
+	moves, err = store.Moves(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != 1 || moves[ids[1]] != label {
+		t.Errorf("moves after labeling %+v, want %+v", moves, label)
+	}
+}
+
+func testStoreFrontier(t *testing.T, store PositionStore, start *components.ChessBoard, children []*components.ChessBoard, ids []int) {
+	for _, enqueue := range []struct {
+		depth int
+		ids   []int
+	}{
+		{1, []int{ids[3], ids[1]}},
+		{2, []int{ids[0]}},
+		{1, []int{ids[2], ids[0]}},
+	} {
+		if err := store.Enqueue(enqueue.depth, enqueue.ids); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := store.Pending(1); err != nil || n != 4 {
+		t.Errorf("Pending(1) = %d, %v; want 4", n, err)
+	}
+
+	for _, want := range [][]int{{ids[3], ids[1], ids[2]}, {ids[0]}, nil} {
+		got, err := store.Dequeue(1, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Dequeue %v, want %v", got, want)
+		}
+	}
+	if n, err := store.Pending(2); err != nil || n != 1 {
+		t.Errorf("Pending(2) = %d, %v; want 1", n, err)
+	}
+}
+
+func testStoreMetadata(t *testing.T, store PositionStore, start *components.ChessBoard, children []*components.ChessBoard, ids []int) {
+	meta, err := store.Metadata(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta != (NodeMetadata{}) {
+		t.Errorf("new position has metadata %+v", meta)
+	}
+
+	value, best, status := 35, ids[2], "White forces mate"
+	if err := store.SetMetadata(ids[0], NodeMetadata{MinimaxValue: &value, BestChild: &best}); err != nil {
+		t.Fatal(err)
+	}
+	// Unset fields leave the stored ones alone
+	if err := store.SetMetadata(ids[0], NodeMetadata{ProofStatus: &status}); err != nil {
+		t.Fatal(err)
+	}
+	value = 0
+
+	meta, err = store.Metadata(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.MinimaxValue == nil || *meta.MinimaxValue != 35 ||
+		meta.BestChild == nil || *meta.BestChild != ids[2] ||
+		meta.ProofStatus == nil || *meta.ProofStatus != status {
+		t.Errorf("metadata %+v does not round-trip", meta)
+	}
+	if err := store.SetMetadata(ids[3]+100, NodeMetadata{ProofStatus: &status}); err == nil {
+		t.Error("no error setting metadata of a missing position")
+	}
+}
+
+func testStoreEdgeStats(t *testing.T, store PositionStore, start *components.ChessBoard, children []*components.ChessBoard, ids []int) {
+	sans := start.SANs()
+	for i, child := range children {
+		if err := store.AddEdge(ids[0], ids[i+1], newMoveLabel(start, sans, child.LastMove)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats, err := store.EdgeStats(ids[0]); err != nil || len(stats) != 0 {
+		t.Errorf("unexpanded edges have statistics %v, %v", stats, err)
+	}
+
+	for i, prior := range []float64{0.5, 0.3} {
+		if err := store.SetPrior(ids[0], ids[i+2], prior); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, value := range []float64{1, -0.5} {
+		if err := store.AddVisit(ids[0], ids[3], value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := store.EdgeStats(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EdgeStat{
+		{ChildID: ids[2], Prior: 0.5},
+		{ChildID: ids[3], Visits: 2, ValueSum: 0.5, Prior: 0.3},
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("EdgeStats %+v, want %+v", stats, want)
+	}
+	if mean := stats[1].MeanValue(); mean != 0.25 {
+		t.Errorf("MeanValue %v, want 0.25", mean)
+	}
+
+	// The most visited children come first
+	got, err := store.Children(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{ids[3], ids[1], ids[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Children %v, want %v", got, want)
+	}
+
+	if err := store.AddVisit(ids[1], ids[0], 1); err == nil {
+		t.Error("no error visiting a missing edge")
+	}
+}
@@ -0,0 +1,91 @@
+package tablebase
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// A table file starts with a header followed by one byte per position, all
+// positions with white to move first:
+//
+//	magic "CCTB", version byte, piece count byte,
+//	one byte per piece (kind, plus 8 for white), positions per side (uint32 LE)
+const (
+	fileMagic     = "CCTB"
+	fileVersion   = 1
+	fileExtension = ".cctb"
+)
+
+func writeTable(path string, t *Table) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating table file: %v", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	header := append([]byte(fileMagic), fileVersion, byte(len(t.pieces)))
+	for _, p := range t.pieces {
+		header = append(header, pieceCode(p))
+	}
+	header = binary.LittleEndian.AppendUint32(header, uint32(t.size))
+	if _, err := w.Write(header); err != nil {
+		return fmt.Errorf("error writing table %s: %v", t.Name, err)
+	}
+	for _, data := range t.data {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("error writing table %s: %v", t.Name, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error writing table %s: %v", t.Name, err)
+	}
+	return file.Close()
+}
+
+// readTable loads the table called name from path and checks that the
+// header matches its pieces. A missing file returns an os.IsNotExist error.
+func readTable(path, name string, pieces []piece) (*Table, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r := bufio.NewReader(file)
+
+	header := make([]byte, len(fileMagic)+2+len(pieces)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("error reading table %s: %v", name, err)
+	}
+	if string(header[:4]) != fileMagic || header[4] != fileVersion || int(header[5]) != len(pieces) {
+		return nil, fmt.Errorf("%s is not a version %d table for %s", path, fileVersion, name)
+	}
+	for i, p := range pieces {
+		if header[6+i] != pieceCode(p) {
+			return nil, fmt.Errorf("%s does not hold the pieces of %s", path, name)
+		}
+	}
+
+	t := &Table{Name: name, layout: newLayout(pieces)}
+	if size := binary.LittleEndian.Uint32(header[6+len(pieces):]); int(size) != t.size {
+		return nil, fmt.Errorf("%s has %d positions per side, expected %d", path, size, t.size)
+	}
+	for side := range t.data {
+		t.data[side] = make([]byte, t.size)
+		if _, err := io.ReadFull(r, t.data[side]); err != nil {
+			return nil, fmt.Errorf("error reading table %s: %v", name, err)
+		}
+	}
+	return t, nil
+}
+
+func pieceCode(p piece) byte {
+	code := byte(p.kind)
+	if p.white {
+		code += 8
+	}
+	return code
+}
@@ -0,0 +1,162 @@
+package tablebase
+
+import "fmt"
+
+// generate solves every position of a table by retrograde analysis. The
+// tables reached by captures and promotions must already be loaded.
+//
+// Positions are settled in order of their distance to mate. Checkmates are
+// losses in 0 plies; every predecessor of a loss in n plies is a win in n+1,
+// and a position all of whose moves lead to wins for the opponent is a loss
+// one ply after the longest of them. Moves that capture or promote are scored
+// from the smaller tables up front. What is never settled is a draw.
+func (s *Set) generate(name string, pieces []piece) (*Table, error) {
+	t := &Table{Name: name, layout: newLayout(pieces)}
+	size := t.size
+
+	// Entries are numbered side*size + index, white to move first
+	values := make([]byte, 2*size)
+	// Moves within the table not yet known to lose, plus one if a capture or
+	// promotion avoids losing
+	remaining := make([]byte, 2*size)
+	// Longest known distance among the losing moves, plus one
+	longestLoss := make([]byte, 2*size)
+
+	var buckets [][]uint32
+	push := func(plies int, entry int) error {
+		if plies > maxPlies {
+			return fmt.Errorf("%s: distance to mate exceeds %d plies", name, maxPlies)
+		}
+		for len(buckets) <= plies {
+			buckets = append(buckets, nil)
+		}
+		buckets[plies] = append(buckets[plies], uint32(entry))
+		return nil
+	}
+
+	var pos position
+	var seen []int
+	for entry := 0; entry < 2*size; entry++ {
+		index := entry % size
+		t.decode(index, entry < size, &pos)
+		if !pos.valid() || t.index(&pos) != index {
+			values[entry] = byte(Invalid)
+			continue
+		}
+
+		children := pos.legalMoves()
+		if len(children) == 0 {
+			if pos.inCheck(pos.whiteToMove) {
+				if err := push(0, entry); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		seen = seen[:0]
+		escape, loss, win := false, 0, -1
+		for i := range children {
+			c := &children[i]
+			if !c.conversion {
+				if child := t.entry(&c.pos); !contains(seen, child) {
+					seen = append(seen, child)
+				}
+				continue
+			}
+
+			v, ok := s.probe(&c.pos)
+			if !ok {
+				missing, _ := materialName(&c.pos)
+				return nil, fmt.Errorf("%s: table %s is not loaded", name, missing)
+			}
+			switch {
+			case v.Loss():
+				escape = true
+				if win < 0 || v.Plies()+1 < win {
+					win = v.Plies() + 1
+				}
+			case v.Win():
+				if v.Plies()+1 > loss {
+					loss = v.Plies() + 1
+				}
+			default:
+				escape = true
+			}
+		}
+
+		remaining[entry] = byte(len(seen))
+		if escape {
+			remaining[entry]++
+		}
+		longestLoss[entry] = byte(loss)
+
+		var err error
+		if win >= 0 {
+			err = push(win, entry)
+		} else if remaining[entry] == 0 {
+			err = push(loss, entry)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for plies := 0; plies < len(buckets); plies++ {
+		for _, entry := range buckets[plies] {
+			if values[entry] != 0 {
+				continue
+			}
+			values[entry] = byte(plies + 1)
+
+			t.decode(int(entry)%size, int(entry) < size, &pos)
+			seen = seen[:0]
+			for _, parent := range pos.unmoves() {
+				parentEntry := t.entry(&parent)
+				if values[parentEntry] != 0 || contains(seen, parentEntry) {
+					continue
+				}
+				seen = append(seen, parentEntry)
+
+				var err error
+				if plies%2 == 0 {
+					err = push(plies+1, parentEntry)
+				} else {
+					remaining[parentEntry]--
+					if longestLoss[parentEntry] < byte(plies+1) {
+						longestLoss[parentEntry] = byte(plies + 1)
+					}
+					if remaining[parentEntry] == 0 {
+						err = push(int(longestLoss[parentEntry]), parentEntry)
+					}
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		buckets[plies] = nil
+	}
+
+	t.data[0] = values[:size]
+	t.data[1] = values[size:]
+	return t, nil
+}
+
+// entry returns the number of pos in the generator's combined table
+func (t *Table) entry(pos *position) int {
+	entry := t.index(pos)
+	if !pos.whiteToMove {
+		entry += t.size
+	}
+	return entry
+}
+
+func contains(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package tablebase
+
+// Positions are indexed by the white king's square within a region that
+// every position can be mirrored into, followed by the squares of the other
+// pieces in table order. Without pawns the board has eight symmetries and the
+// white king fits in the a1-d1-d4 triangle (10 squares); with pawns only the
+// left-right mirror applies and the white king stays on files a to d
+// (32 squares). Side to move is kept in separate halves of the table.
+
+const (
+	flipFile = 1 << iota
+	flipRank
+	flipDiagonal
+)
+
+// transformed[t][sq] is sq under the symmetry t, a combination of the flip
+// bits applied in that order
+var transformed [8][64]int8
+
+// kingRegion maps a square to its index within the white king region, or -1
+var kingRegion [2][64]int
+
+// regionSquares is the inverse of kingRegion
+var regionSquares [2][]int8
+
+// kingTransforms lists for every white king square the symmetries that move
+// it into the region
+var kingTransforms [2][64][]int
+
+func init() {
+	for t := 0; t < 8; t++ {
+		for sq := 0; sq < 64; sq++ {
+			file, rank := sq%8, sq/8
+			if t&flipFile != 0 {
+				file = 7 - file
+			}
+			if t&flipRank != 0 {
+				rank = 7 - rank
+			}
+			if t&flipDiagonal != 0 {
+				file, rank = rank, file
+			}
+			transformed[t][sq] = int8(rank*8 + file)
+		}
+	}
+
+	for withPawns := 0; withPawns < 2; withPawns++ {
+		for sq := 0; sq < 64; sq++ {
+			file, rank := sq%8, sq/8
+			inRegion := file <= 3 && rank <= file
+			if withPawns == 1 {
+				inRegion = file <= 3
+			}
+			kingRegion[withPawns][sq] = -1
+			if inRegion {
+				kingRegion[withPawns][sq] = len(regionSquares[withPawns])
+				regionSquares[withPawns] = append(regionSquares[withPawns], int8(sq))
+			}
+		}
+
+		symmetries := 8
+		if withPawns == 1 {
+			symmetries = 2
+		}
+		for sq := 0; sq < 64; sq++ {
+			for t := 0; t < symmetries; t++ {
+				if kingRegion[withPawns][transformed[t][sq]] >= 0 {
+					kingTransforms[withPawns][sq] = append(kingTransforms[withPawns][sq], t)
+				}
+			}
+		}
+	}
+}
+
+// layout describes the pieces of a table in index order: white king, black
+// king, then the other white pieces and the other black pieces, strongest
+// first
+type layout struct {
+	pieces []piece
+	pawns  int // 1 when the table has pawns, selecting the king region
+	size   int // positions per side to move
+}
+
+func newLayout(pieces []piece) layout {
+	l := layout{pieces: pieces}
+	for _, p := range pieces {
+		if p.kind == pawn {
+			l.pawns = 1
+		}
+	}
+	l.size = len(regionSquares[l.pawns])
+	for i := 1; i < len(pieces); i++ {
+		l.size *= 64
+	}
+	return l
+}
+
+// index returns the index of pos, whose pieces are in layout order. All
+// positions related by symmetry or by swapping identical pieces get the same
+// index: the smallest over the symmetries that put the white king in its
+// region.
+func (l *layout) index(pos *position) int {
+	best := -1
+	for _, t := range kingTransforms[l.pawns][pos.squares[0]] {
+		var squares [maxPieces]int8
+		for i := 0; i < pos.n; i++ {
+			squares[i] = transformed[t][pos.squares[i]]
+		}
+		// Identical pieces can only be the two non-king pieces of one side
+		if pos.n == 4 && l.pieces[2] == l.pieces[3] && squares[2] > squares[3] {
+			squares[2], squares[3] = squares[3], squares[2]
+		}
+
+		index := kingRegion[l.pawns][squares[0]]
+		for i := 1; i < pos.n; i++ {
+			index = index*64 + int(squares[i])
+		}
+		if best < 0 || index < best {
+			best = index
+		}
+	}
+	return best
+}
+
+// decode sets pos to the position at index, which may be invalid or not the
+// canonical index of its position
+func (l *layout) decode(index int, whiteToMove bool, pos *position) {
+	pos.n = len(l.pieces)
+	copy(pos.pieces[:], l.pieces)
+	pos.whiteToMove = whiteToMove
+	for i := pos.n - 1; i >= 1; i-- {
+		pos.squares[i] = int8(index % 64)
+		index /= 64
+	}
+	pos.squares[0] = regionSquares[l.pawns][index]
+}
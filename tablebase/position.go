@@ -0,0 +1,345 @@
+package tablebase
+
+// The generator works on its own small position type instead of
+// components.ChessBoard: with at most four pieces a position is a list of
+// piece squares and an occupancy bitboard, which makes generating millions of
+// positions cheap.
+
+// kind is a piece type, ordered from the strongest non-king piece down so
+// that sorting by kind lists the stronger pieces first
+type kind int8
+
+const (
+	king kind = iota
+	queen
+	rook
+	bishop
+	knight
+	pawn
+)
+
+var kindLetters = [...]byte{'K', 'Q', 'R', 'B', 'N', 'P'}
+
+// noPromotion marks a move that does not promote, as no pawn promotes to a king
+const noPromotion = king
+
+type piece struct {
+	kind  kind
+	white bool
+}
+
+// maxPieces is the largest number of pieces, kings included, in a table
+const maxPieces = 4
+
+// position is a set of pieces on squares 0 (a1) to 63 (h8), numbered
+// rank*8 + file like components.ChessBoard.Board[rank][file]
+type position struct {
+	n           int
+	pieces      [maxPieces]piece
+	squares     [maxPieces]int8
+	whiteToMove bool
+}
+
+func (pos *position) occupancy() uint64 {
+	var occ uint64
+	for i := 0; i < pos.n; i++ {
+		occ |= 1 << uint(pos.squares[i])
+	}
+	return occ
+}
+
+// pieceAt returns the index of the piece on sq, or -1
+func (pos *position) pieceAt(sq int8) int {
+	for i := 0; i < pos.n; i++ {
+		if pos.squares[i] == sq {
+			return i
+		}
+	}
+	return -1
+}
+
+func (pos *position) kingSquare(white bool) int8 {
+	for i := 0; i < pos.n; i++ {
+		if pos.pieces[i].kind == king && pos.pieces[i].white == white {
+			return pos.squares[i]
+		}
+	}
+	return -1
+}
+
+// remove deletes the piece at index i, keeping the order of the others
+func (pos *position) remove(i int) {
+	copy(pos.pieces[i:pos.n], pos.pieces[i+1:pos.n])
+	copy(pos.squares[i:pos.n], pos.squares[i+1:pos.n])
+	pos.n--
+}
+
+var (
+	kingSteps   = [8][2]int8{{1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}, {0, -1}, {1, -1}}
+	knightSteps = [8][2]int8{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}}
+	rookSteps   = [4][2]int8{{1, 0}, {0, 1}, {-1, 0}, {0, -1}}
+	bishopSteps = [4][2]int8{{1, 1}, {-1, 1}, {-1, -1}, {1, -1}}
+)
+
+// offset returns the square df files and dr ranks away from sq, or -1 when
+// that is off the board
+func offset(sq, df, dr int8) int8 {
+	file, rank := sq%8+df, sq/8+dr
+	if file < 0 || file > 7 || rank < 0 || rank > 7 {
+		return -1
+	}
+	return rank*8 + file
+}
+
+// targets calls visit for every square a piece of kind k on sq moves to or
+// attacks, stopping sliders at the first occupied square (which is visited).
+// Pawns are handled separately.
+func targets(k kind, sq int8, occ uint64, visit func(to int8)) {
+	switch k {
+	case king:
+		for _, step := range kingSteps {
+			if to := offset(sq, step[0], step[1]); to >= 0 {
+				visit(to)
+			}
+		}
+	case knight:
+		for _, step := range knightSteps {
+			if to := offset(sq, step[0], step[1]); to >= 0 {
+				visit(to)
+			}
+		}
+	case rook:
+		slide(sq, rookSteps[:], occ, visit)
+	case bishop:
+		slide(sq, bishopSteps[:], occ, visit)
+	case queen:
+		slide(sq, rookSteps[:], occ, visit)
+		slide(sq, bishopSteps[:], occ, visit)
+	}
+}
+
+func slide(sq int8, steps [][2]int8, occ uint64, visit func(to int8)) {
+	for _, step := range steps {
+		for to := offset(sq, step[0], step[1]); to >= 0; to = offset(to, step[0], step[1]) {
+			visit(to)
+			if occ&(1<<uint(to)) != 0 {
+				break
+			}
+		}
+	}
+}
+
+func pawnDirection(white bool) int8 {
+	if white {
+		return 1
+	}
+	return -1
+}
+
+// attacks reports whether the piece at index i attacks sq
+func (pos *position) attacks(i int, sq int8, occ uint64) bool {
+	from := pos.squares[i]
+	p := pos.pieces[i]
+	df, dr := sq%8-from%8, sq/8-from/8
+
+	switch p.kind {
+	case pawn:
+		return dr == pawnDirection(p.white) && (df == 1 || df == -1)
+	case king:
+		return df >= -1 && df <= 1 && dr >= -1 && dr <= 1 && (df != 0 || dr != 0)
+	case knight:
+		return df*df+dr*dr == 5
+	}
+
+	straight := df == 0 || dr == 0
+	diagonal := df == dr || df == -dr
+	if (p.kind == rook && !straight) || (p.kind == bishop && !diagonal) ||
+		(p.kind == queen && !straight && !diagonal) || (df == 0 && dr == 0) {
+		return false
+	}
+	stepF, stepR := sign(df), sign(dr)
+	for to := offset(from, stepF, stepR); to != sq; to = offset(to, stepF, stepR) {
+		if occ&(1<<uint(to)) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sign(x int8) int8 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+// attacked reports whether a piece of the given color attacks sq
+func (pos *position) attacked(sq int8, byWhite bool) bool {
+	occ := pos.occupancy()
+	for i := 0; i < pos.n; i++ {
+		if pos.pieces[i].white == byWhite && pos.attacks(i, sq, occ) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pos *position) inCheck(white bool) bool {
+	return pos.attacked(pos.kingSquare(white), !white)
+}
+
+// valid reports whether pos can occur in a game: no two pieces share a
+// square, no pawn stands on its first or last rank and the side that just
+// moved is not in check
+func (pos *position) valid() bool {
+	var occ uint64
+	for i := 0; i < pos.n; i++ {
+		bit := uint64(1) << uint(pos.squares[i])
+		if occ&bit != 0 {
+			return false
+		}
+		occ |= bit
+		if pos.pieces[i].kind == pawn {
+			if rank := pos.squares[i] / 8; rank == 0 || rank == 7 {
+				return false
+			}
+		}
+	}
+	return !pos.inCheck(!pos.whiteToMove)
+}
+
+// child is a position reached by a legal move. conversion is set when the
+// move captured or promoted, so the child belongs to another table.
+type child struct {
+	pos        position
+	conversion bool
+}
+
+// legalMoves returns every position reachable by a legal move. En passant is
+// not generated: tablebase positions never carry an en passant square.
+func (pos *position) legalMoves() []child {
+	var children []child
+	occ := pos.occupancy()
+	white := pos.whiteToMove
+
+	add := func(i int, to int8, promotion kind) {
+		next := *pos
+		next.whiteToMove = !white
+		next.squares[i] = to
+		conversion := false
+		if promotion != noPromotion {
+			next.pieces[i].kind = promotion
+			conversion = true
+		}
+		if captured := pos.pieceAt(to); captured >= 0 {
+			next.remove(captured)
+			conversion = true
+		}
+		if !next.inCheck(white) {
+			children = append(children, child{pos: next, conversion: conversion})
+		}
+	}
+
+	for i := 0; i < pos.n; i++ {
+		p := pos.pieces[i]
+		if p.white != white {
+			continue
+		}
+		from := pos.squares[i]
+
+		if p.kind != pawn {
+			targets(p.kind, from, occ, func(to int8) {
+				if other := pos.pieceAt(to); other < 0 || (pos.pieces[other].white != white && pos.pieces[other].kind != king) {
+					add(i, to, noPromotion)
+				}
+			})
+			continue
+		}
+
+		dir := pawnDirection(white)
+		pawnMove := func(to int8) {
+			if rank := to / 8; rank == 0 || rank == 7 {
+				for _, promotion := range []kind{queen, rook, bishop, knight} {
+					add(i, to, promotion)
+				}
+			} else {
+				add(i, to, noPromotion)
+			}
+		}
+		if to := offset(from, 0, dir); to >= 0 && occ&(1<<uint(to)) == 0 {
+			pawnMove(to)
+			startRank := int8(1)
+			if !white {
+				startRank = 6
+			}
+			if double := offset(to, 0, dir); from/8 == startRank && occ&(1<<uint(double)) == 0 {
+				add(i, double, noPromotion)
+			}
+		}
+		for _, df := range []int8{-1, 1} {
+			if to := offset(from, df, dir); to >= 0 {
+				if other := pos.pieceAt(to); other >= 0 && pos.pieces[other].white != white && pos.pieces[other].kind != king {
+					pawnMove(to)
+				}
+			}
+		}
+	}
+	return children
+}
+
+// unmoves returns the positions from which a move without capture or
+// promotion leads to pos. Those moves stay within the same table; captures
+// and promotions are found by probing smaller tables from the other side.
+func (pos *position) unmoves() []position {
+	var parents []position
+	occ := pos.occupancy()
+	mover := !pos.whiteToMove
+
+	add := func(i int, from int8) {
+		prev := *pos
+		prev.whiteToMove = mover
+		prev.squares[i] = from
+		if !prev.inCheck(!mover) {
+			parents = append(parents, prev)
+		}
+	}
+
+	for i := 0; i < pos.n; i++ {
+		p := pos.pieces[i]
+		if p.white != mover {
+			continue
+		}
+		to := pos.squares[i]
+
+		if p.kind != pawn {
+			targets(p.kind, to, occ, func(from int8) {
+				if occ&(1<<uint(from)) == 0 {
+					add(i, from)
+				}
+			})
+			continue
+		}
+
+		dir := pawnDirection(mover)
+		from := offset(to, 0, -dir)
+		if from < 0 || occ&(1<<uint(from)) != 0 {
+			continue
+		}
+		if rank := from / 8; rank != 0 && rank != 7 {
+			add(i, from)
+		}
+		doubleRank := int8(3)
+		if !mover {
+			doubleRank = 4
+		}
+		if to/8 == doubleRank {
+			if double := offset(from, 0, -dir); occ&(1<<uint(double)) == 0 {
+				add(i, double)
+			}
+		}
+	}
+	return parents
+}
@@ -0,0 +1,131 @@
+package tablebase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+// Probe returns the value of board for its side to move. ok is false when
+// the board is not standard chess, has more than four pieces, castling
+// rights or an en passant square, is not a legal position, or its table is
+// not available. Probe never generates tables; missing ones are read from
+// Dir on first use.
+func (s *Set) Probe(board *components.ChessBoard) (Value, bool) {
+	if board.Variant != "" || board.EnPassant != nil || board.Castling != (components.CastlingRights{}) {
+		return Invalid, false
+	}
+
+	pos := position{whiteToMove: board.NextTurn}
+	kings := 0
+	for y, row := range board.Board {
+		for x, p := range row {
+			if p == nil {
+				continue
+			}
+			if pos.n == maxPieces {
+				return Invalid, false
+			}
+			k := letterKind(p.ToString()[3])
+			if k < 0 {
+				return Invalid, false
+			}
+			if k == king {
+				kings++
+			}
+			pos.pieces[pos.n] = piece{kind: k, white: p.GetColor()}
+			pos.squares[pos.n] = int8(y*8 + x)
+			pos.n++
+		}
+	}
+	if kings != 2 || pos.kingSquare(true) < 0 || pos.kingSquare(false) < 0 {
+		return Invalid, false
+	}
+
+	v, ok := s.probe(&pos)
+	if v == Invalid {
+		return Invalid, false
+	}
+	return v, ok
+}
+
+func letterKind(letter byte) kind {
+	for k, l := range kindLetters {
+		if l == letter {
+			return kind(k)
+		}
+	}
+	return -1
+}
+
+// loaded returns a table that is in memory or can be read from Dir,
+// remembering tables that are missing so they are only looked for once
+func (s *Set) loaded(name string) (*Table, bool) {
+	s.mu.RLock()
+	table, ok := s.tables[name]
+	missing := s.missing[name]
+	s.mu.RUnlock()
+	if ok || missing || s.Dir == "" {
+		return table, ok
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if table, ok := s.tables[name]; ok {
+		return table, true
+	}
+	pieces, _, err := parseName(name)
+	if err == nil {
+		table, err = readTable(filepath.Join(s.Dir, name+fileExtension), name, pieces)
+	}
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error loading tablebase %s: %v\n", name, err)
+		}
+		s.missing[name] = true
+		return nil, false
+	}
+	s.tables[name] = table
+	return table, true
+}
+
+// WDL returns 1 for a win, 0 for a draw and -1 for a loss of the side to move
+func (v Value) WDL() int {
+	switch {
+	case v.Win():
+		return 1
+	case v.Loss():
+		return -1
+	}
+	return 0
+}
+
+// Score converts v into a search score in the components.MateScore
+// convention for a position ply plies from the root
+func (v Value) Score(ply int) int {
+	switch {
+	case v.Win():
+		return components.MateScore - ply - v.Plies()
+	case v.Loss():
+		return -components.MateScore + ply + v.Plies()
+	}
+	return 0
+}
+
+// Describe renders v for a position with the given side to move, such as
+// "White wins, mate in 17"
+func Describe(v Value, whiteToMove bool) string {
+	if !v.Win() && !v.Loss() {
+		return "Draw"
+	}
+	winner := "White"
+	if whiteToMove == v.Loss() {
+		winner = "Black"
+	}
+	if v.Plies() == 0 {
+		return winner + " wins, checkmate"
+	}
+	return fmt.Sprintf("%s wins, mate in %d", winner, v.Moves())
+}
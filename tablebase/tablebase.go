@@ -0,0 +1,411 @@
+// Package tablebase generates and reads endgame tablebases for positions
+// with up to four pieces, kings included. Every position of a material
+// combination such as KQvK or KRvKP is solved by retrograde analysis into a
+// win, draw or loss for the side to move with its distance to mate (DTM).
+//
+// Tables are named after their material with the stronger side as white;
+// positions where black has the stronger material are looked up with the
+// colors flipped. Castling rights, en passant and the fifty move rule are
+// ignored.
+package tablebase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Value is a tablebase result for the side to move. Non-zero values other
+// than Invalid hold the distance to mate in plies plus one: an odd distance
+// is a win for the side to move, an even one a loss.
+type Value byte
+
+const (
+	Draw Value = 0
+
+	// Invalid marks illegal positions and indices that are not the canonical
+	// index of their position
+	Invalid Value = 255
+
+	// maxPlies is the longest distance to mate a Value can hold
+	maxPlies = 253
+)
+
+func (v Value) Win() bool {
+	return v != Draw && v != Invalid && (v-1)%2 == 1
+}
+
+func (v Value) Loss() bool {
+	return v != Draw && v != Invalid && (v-1)%2 == 0
+}
+
+// Plies returns the distance to mate in plies of a win or loss
+func (v Value) Plies() int {
+	return int(v) - 1
+}
+
+// Moves returns the distance to mate in moves of the winning side
+func (v Value) Moves() int {
+	return (v.Plies() + 1) / 2
+}
+
+func (v Value) String() string {
+	switch {
+	case v == Draw:
+		return "draw"
+	case v == Invalid:
+		return "invalid"
+	case v.Win():
+		return fmt.Sprintf("win, mate in %d", v.Moves())
+	default:
+		return fmt.Sprintf("loss, mated in %d", v.Moves())
+	}
+}
+
+// Table holds the values of every position of one material combination
+type Table struct {
+	Name string
+	layout
+
+	// data[0] holds the positions with white to move, data[1] black
+	data [2][]byte
+}
+
+// value returns the value of pos, whose pieces are in the table's order
+func (t *Table) value(pos *position) Value {
+	side := 0
+	if !pos.whiteToMove {
+		side = 1
+	}
+	return Value(t.data[side][t.index(pos)])
+}
+
+// LongestWin returns the longest distance to mate of any won position in
+// the table, for either side to move
+func (t *Table) LongestWin() Value {
+	longest := Draw
+	for side := range t.data {
+		for _, entry := range t.data[side] {
+			if v := Value(entry); v.Win() && v > longest {
+				longest = v
+			}
+		}
+	}
+	return longest
+}
+
+// Set is a collection of tables. Missing tables are read from Dir, or
+// generated together with the smaller tables they depend on and written to
+// Dir. An empty Dir keeps everything in memory.
+type Set struct {
+	Dir string
+
+	mu      sync.RWMutex
+	tables  map[string]*Table
+	missing map[string]bool // tables Probe found neither loaded nor in Dir
+}
+
+func NewSet(dir string) *Set {
+	return &Set{Dir: dir, tables: make(map[string]*Table), missing: make(map[string]bool)}
+}
+
+// Table returns the table for a material name such as "KRvKP"
+func (s *Set) Table(name string) (*Table, error) {
+	pieces, name, err := parseName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	table, ok := s.tables[name]
+	s.mu.RUnlock()
+	if ok {
+		return table, nil
+	}
+
+	path := ""
+	if s.Dir != "" {
+		path = filepath.Join(s.Dir, name+fileExtension)
+		if table, err = readTable(path, name, pieces); err == nil {
+			s.add(table)
+			return table, nil
+		} else if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	for _, dependency := range dependencies(pieces) {
+		if _, err := s.Table(dependency); err != nil {
+			return nil, err
+		}
+	}
+	if table, err = s.generate(name, pieces); err != nil {
+		return nil, err
+	}
+	if path != "" {
+		if err := writeTable(path, table); err != nil {
+			return nil, err
+		}
+	}
+	s.add(table)
+	return table, nil
+}
+
+func (s *Set) add(table *Table) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables[table.Name] = table
+	delete(s.missing, table.Name)
+}
+
+// probe looks pos up in the loaded tables, or those in Dir. Bare kings are
+// a draw.
+func (s *Set) probe(pos *position) (Value, bool) {
+	if pos.n == 2 {
+		return Draw, true
+	}
+
+	name, flip := materialName(pos)
+	table, ok := s.loaded(name)
+	if !ok {
+		return Invalid, false
+	}
+
+	oriented := *pos
+	if flip {
+		oriented.flipColors()
+	}
+	oriented.sortPieces()
+	return table.value(&oriented), true
+}
+
+// flipColors swaps the colors of all pieces and mirrors the board vertically,
+// which gives a position with the same result for the side to move
+func (pos *position) flipColors() {
+	for i := 0; i < pos.n; i++ {
+		pos.pieces[i].white = !pos.pieces[i].white
+		pos.squares[i] ^= 56
+	}
+	pos.whiteToMove = !pos.whiteToMove
+}
+
+// sortPieces puts the pieces in table order: white king, black king, other
+// white pieces and other black pieces, strongest first
+func (pos *position) sortPieces() {
+	order := func(p piece) int {
+		switch {
+		case p.kind == king && p.white:
+			return 0
+		case p.kind == king:
+			return 1
+		case p.white:
+			return 2 + int(p.kind)
+		default:
+			return 10 + int(p.kind)
+		}
+	}
+	for i := 1; i < pos.n; i++ {
+		for j := i; j > 0 && order(pos.pieces[j]) < order(pos.pieces[j-1]); j-- {
+			pos.pieces[j], pos.pieces[j-1] = pos.pieces[j-1], pos.pieces[j]
+			pos.squares[j], pos.squares[j-1] = pos.squares[j-1], pos.squares[j]
+		}
+	}
+}
+
+// materialName returns the table name of pos and whether its colors have to
+// be flipped to match the table
+func materialName(pos *position) (string, bool) {
+	var white, black []kind
+	for i := 0; i < pos.n; i++ {
+		if p := pos.pieces[i]; p.kind != king {
+			if p.white {
+				white = append(white, p.kind)
+			} else {
+				black = append(black, p.kind)
+			}
+		}
+	}
+	sortKinds(white)
+	sortKinds(black)
+	if stronger(black, white) {
+		return sideName(black) + "v" + sideName(white), true
+	}
+	return sideName(white) + "v" + sideName(black), false
+}
+
+func sortKinds(kinds []kind) {
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+}
+
+// stronger reports whether the sorted material a outranks b: more pieces,
+// or the stronger piece at the first difference
+func stronger(a, b []kind) bool {
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func sideName(kinds []kind) string {
+	name := []byte{'K'}
+	for _, k := range kinds {
+		name = append(name, kindLetters[k])
+	}
+	return string(name)
+}
+
+// parseName reads a material name such as "KQvKR" and returns the pieces in
+// table order together with the canonical name
+func parseName(name string) ([]piece, string, error) {
+	sides := strings.Split(strings.ToUpper(name), "V")
+	if len(sides) != 2 {
+		return nil, "", fmt.Errorf("invalid table name %q", name)
+	}
+
+	var pos position
+	for side, text := range sides {
+		if len(text) == 0 || text[0] != 'K' {
+			return nil, "", fmt.Errorf("invalid table name %q: each side needs a king first", name)
+		}
+		for i := 0; i < len(text); i++ {
+			k := kind(strings.IndexByte(string(kindLetters[:]), text[i]))
+			if k < 0 || (k == king) != (i == 0) {
+				return nil, "", fmt.Errorf("invalid table name %q", name)
+			}
+			if pos.n == maxPieces {
+				return nil, "", fmt.Errorf("table %q has more than %d pieces", name, maxPieces)
+			}
+			pos.pieces[pos.n] = piece{kind: k, white: side == 0}
+			pos.n++
+		}
+	}
+
+	canonical, flip := materialName(&pos)
+	if flip {
+		pos.flipColors()
+	}
+	pos.sortPieces()
+	return append([]piece(nil), pos.pieces[:pos.n]...), canonical, nil
+}
+
+// dependencies returns the tables reached by one capture or promotion
+func dependencies(pieces []piece) []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(pos *position) {
+		if pos.n == 2 {
+			return
+		}
+		if name, _ := materialName(pos); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	var pos position
+	pos.n = len(pieces)
+	copy(pos.pieces[:], pieces)
+	for i := 2; i < pos.n; i++ {
+		captured := pos
+		captured.remove(i)
+		add(&captured)
+
+		if pos.pieces[i].kind == pawn {
+			for _, promotion := range []kind{queen, rook, bishop, knight} {
+				promoted := pos
+				promoted.pieces[i].kind = promotion
+				add(&promoted)
+			}
+		}
+	}
+	return names
+}
+
+// AllNames lists every table with up to four pieces, ordered so that each
+// table comes after the tables it depends on
+func AllNames() []string {
+	extras := [][]kind{{}}
+	for a := queen; a <= pawn; a++ {
+		extras = append(extras, []kind{a})
+		for b := a; b <= pawn; b++ {
+			extras = append(extras, []kind{a, b})
+		}
+	}
+
+	type entry struct {
+		name          string
+		pieces, pawns int
+	}
+	var entries []entry
+	for _, white := range extras {
+		for _, black := range extras {
+			if len(white)+len(black) == 0 || len(white)+len(black) > maxPieces-2 || stronger(black, white) {
+				continue
+			}
+			e := entry{name: sideName(white) + "v" + sideName(black), pieces: 2 + len(white) + len(black)}
+			for _, k := range append(append([]kind(nil), white...), black...) {
+				if k == pawn {
+					e.pawns++
+				}
+			}
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].pieces != entries[j].pieces {
+			return entries[i].pieces < entries[j].pieces
+		}
+		return entries[i].pawns < entries[j].pawns
+	})
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// KnownLongestMates are published longest distances to mate, in moves, used
+// by Verify to check the generator
+var KnownLongestMates = []struct {
+	Name  string
+	Moves int
+}{
+	{"KQvK", 10},
+	{"KRvK", 16},
+	{"KPvK", 28},
+	{"KBBvK", 19},
+	{"KBNvK", 33},
+	{"KQvKR", 35},
+}
+
+// Verify generates the tables of KnownLongestMates and compares their
+// longest mates with the published values
+func (s *Set) Verify(report func(name string, want, got int)) error {
+	failed := 0
+	for _, known := range KnownLongestMates {
+		table, err := s.Table(known.Name)
+		if err != nil {
+			return err
+		}
+		got := table.LongestWin().Moves()
+		if report != nil {
+			report(known.Name, known.Moves, got)
+		}
+		if got != known.Moves {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tables have a wrong longest mate", failed, len(KnownLongestMates))
+	}
+	return nil
+}
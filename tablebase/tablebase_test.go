@@ -0,0 +1,69 @@
+package tablebase
+
+import (
+	"testing"
+
+	"github.com/LIAMBB/chess-compute/components"
+)
+
+func TestLongestMates(t *testing.T) {
+	set := NewSet("")
+	for _, known := range []struct {
+		name  string
+		moves int
+	}{
+		{"KQvK", 10},
+		{"KRvK", 16},
+	} {
+		table, err := set.Table(known.name)
+		if err != nil {
+			t.Fatalf("generating %s: %v", known.name, err)
+		}
+		if got := table.LongestWin().Moves(); got != known.moves {
+			t.Errorf("%s longest mate %d, want %d", known.name, got, known.moves)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	set := NewSet("")
+	if _, err := set.Table("KQvK"); err != nil {
+		t.Fatalf("generating KQvK: %v", err)
+	}
+
+	for _, test := range []struct {
+		fen   string
+		wdl   int
+		plies int
+	}{
+		{"k7/8/1K6/8/8/7Q/8/8 w - - 0 1", 1, 1},   // Qh8#
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 0 1", -1, 0}, // checkmated
+		{"k7/2Q5/1K6/8/8/8/8/8 b - - 0 1", 0, 0},  // stalemate
+		{"k7/8/1K6/8/8/7Q/8/8 b - - 0 1", -1, 2},  // Kb8 Qh8#
+	} {
+		board, err := components.ParseFEN(test.fen)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", test.fen, err)
+		}
+		v, ok := set.Probe(&board)
+		if !ok {
+			t.Errorf("%s: not found", test.fen)
+			continue
+		}
+		if v.WDL() != test.wdl {
+			t.Errorf("%s: WDL %d, want %d", test.fen, v.WDL(), test.wdl)
+		}
+		if test.wdl != 0 && v.Plies() != test.plies {
+			t.Errorf("%s: mate in %d plies, want %d", test.fen, v.Plies(), test.plies)
+		}
+	}
+
+	// Black holding the queen is looked up with the colors flipped
+	board, err := components.ParseFEN("6q1/8/8/8/8/1k6/8/K7 b - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := set.Probe(&board); !ok || v.WDL() != 1 || v.Plies() != 1 {
+		t.Errorf("flipped KQvK: %v, %v; want mate in 1 ply", v, ok)
+	}
+}
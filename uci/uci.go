@@ -0,0 +1,378 @@
+// Package uci speaks the Universal Chess Interface protocol on top of the
+// search package, so the engine can be driven by chess GUIs and test harnesses.
+package uci
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/search"
+	"github.com/LIAMBB/chess-compute/tablebase"
+)
+
+const (
+	EngineName   = "chess-compute"
+	EngineAuthor = "LIAMBB"
+
+	// Upper bound of the MultiPV option, more than any position's legal moves
+	maxMultiPV = 256
+)
+
+// Session holds the protocol state of one UCI connection
+type Session struct {
+	out   io.Writer
+	outMu sync.Mutex
+
+	engine   *search.Engine
+	board    components.ChessBoard
+	multiPV  int
+	chess960 bool
+	variant  string // UCI_Variant, empty for standard chess
+
+	// Set while a search goroutine is running
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewSession(out io.Writer) *Session {
+	board, _ := components.ParseFEN(components.StartFEN)
+	return &Session{
+		out:     out,
+		engine:  search.NewEngine(),
+		board:   board,
+		multiPV: 1,
+	}
+}
+
+// Run reads commands from in until "quit" or end of input, writing responses
+// to out. A running search is stopped before Run returns.
+func Run(in io.Reader, out io.Writer) error {
+	session := NewSession(out)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !session.Handle(scanner.Text()) {
+			return nil
+		}
+	}
+	session.stopSearch()
+	return scanner.Err()
+}
+
+// Handle processes one command line and reports whether the session should
+// keep reading commands
+func (s *Session) Handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+
+	switch fields[0] {
+	case "uci":
+		s.send("id name %s", EngineName)
+		s.send("id author %s", EngineAuthor)
+		s.send("option name Hash type spin default %d min 1 max 4096", search.DefaultHashMB)
+		s.send("option name MultiPV type spin default 1 min 1 max %d", maxMultiPV)
+		s.send("option name TablebasePath type string default <empty>")
+		s.send("option name UCI_Chess960 type check default false")
+		s.send("option name UCI_Variant type combo default standard var %s", strings.Join(components.VariantNames(), " var "))
+		s.send("uciok")
+	case "isready":
+		s.send("readyok")
+	case "ucinewgame":
+		s.stopSearch()
+		s.engine.TT.Clear()
+	case "setoption":
+		s.stopSearch()
+		s.setOption(fields[1:])
+	case "position":
+		s.stopSearch()
+		if err := s.setPosition(fields[1:]); err != nil {
+			s.send("info string %v", err)
+		}
+	case "go":
+		s.stopSearch()
+		s.startSearch(parseGo(fields[1:]))
+	case "stop":
+		s.stopSearch()
+	case "quit":
+		s.stopSearch()
+		return false
+	default:
+		s.send("info string unknown command %s", fields[0])
+	}
+	return true
+}
+
+func (s *Session) send(format string, args ...interface{}) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+// setOption handles "setoption name <id> [value <x>]"
+func (s *Session) setOption(fields []string) {
+	var name, value []string
+	target := &name
+	for _, field := range fields {
+		switch field {
+		case "name":
+			target = &name
+		case "value":
+			target = &value
+		default:
+			*target = append(*target, field)
+		}
+	}
+
+	switch strings.ToLower(strings.Join(name, " ")) {
+	case "hash":
+		size, err := strconv.Atoi(strings.Join(value, " "))
+		if err != nil || size < 1 {
+			s.send("info string invalid Hash value %q", strings.Join(value, " "))
+			return
+		}
+		s.engine.TT = search.NewTranspositionTable(size)
+	case "multipv":
+		lines, err := strconv.Atoi(strings.Join(value, " "))
+		if err != nil || lines < 1 || lines > maxMultiPV {
+			s.send("info string invalid MultiPV value %q", strings.Join(value, " "))
+			return
+		}
+		s.multiPV = lines
+	case "tablebasepath":
+		s.engine.Tablebases = nil
+		if path := strings.Join(value, " "); path != "" && path != "<empty>" {
+			s.engine.Tablebases = tablebase.NewSet(path)
+		}
+	case "uci_chess960":
+		chess960 := strings.Join(value, " ") == "true"
+		if chess960 != s.chess960 {
+			// Hashes ignore Chess960, so entries from the other rules
+			// would be probed as if they applied
+			s.engine.TT.Clear()
+		}
+		s.chess960 = chess960
+	case "uci_variant":
+		variant, err := components.VariantByName(strings.Join(value, " "))
+		if err != nil {
+			s.send("info string %v", err)
+			return
+		}
+		name := ""
+		if _, standard := variant.(components.Standard); !standard {
+			name = variant.Name()
+		}
+		if name != s.variant {
+			// Hashes ignore the variant, as they do Chess960
+			s.engine.TT.Clear()
+		}
+		s.variant = name
+	default:
+		s.send("info string unknown option %q", strings.Join(name, " "))
+	}
+}
+
+// setPosition handles "position [startpos | fen <fen>] [moves <move>...]"
+func (s *Session) setPosition(fields []string) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("position needs startpos or fen")
+	}
+
+	// startpos is the start position of the selected variant
+	fen := components.StartFEN
+	if variant, err := components.VariantByName(s.variant); err == nil {
+		start := variant.StartPosition()
+		fen = start.ToFEN()
+	}
+	rest := fields[1:]
+	switch fields[0] {
+	case "startpos":
+	case "fen":
+		end := len(rest)
+		for i, field := range rest {
+			if field == "moves" {
+				end = i
+				break
+			}
+		}
+		fen = strings.Join(rest[:end], " ")
+		rest = rest[end:]
+	default:
+		return fmt.Errorf("position needs startpos or fen, got %q", fields[0])
+	}
+
+	board, err := components.ParseFEN(fen)
+	if err != nil {
+		return err
+	}
+	// Castling moves are then sent as the king taking its rook
+	if s.chess960 {
+		board.Chess960 = true
+	}
+	if s.variant != "" {
+		board.Variant = s.variant
+	}
+	if len(rest) > 0 && rest[0] == "moves" {
+		for _, text := range rest[1:] {
+			next, err := board.ApplyUCIMove(text)
+			if err != nil {
+				return err
+			}
+			board = *next
+		}
+	}
+
+	s.board = board
+	return nil
+}
+
+// goParams are the arguments of a "go" command. Times are in milliseconds.
+type goParams struct {
+	depth     int
+	moveTime  int
+	wtime     int
+	btime     int
+	winc      int
+	binc      int
+	movesToGo int
+	infinite  bool
+}
+
+func parseGo(fields []string) goParams {
+	var params goParams
+	for i := 0; i < len(fields); i++ {
+		value := 0
+		if i+1 < len(fields) {
+			value, _ = strconv.Atoi(fields[i+1])
+		}
+		switch fields[i] {
+		case "infinite":
+			params.infinite = true
+			continue
+		case "depth":
+			params.depth = value
+		case "movetime":
+			params.moveTime = value
+		case "wtime":
+			params.wtime = value
+		case "btime":
+			params.btime = value
+		case "winc":
+			params.winc = value
+		case "binc":
+			params.binc = value
+		case "movestogo":
+			params.movesToGo = value
+		default:
+			continue
+		}
+		i++
+	}
+	return params
+}
+
+// timeManager returns the time limits of the search, or nil for none
+func (params goParams) timeManager(whiteToMove bool) *search.TimeManager {
+	if params.infinite {
+		return nil
+	}
+	if params.moveTime > 0 {
+		return search.NewTimeManager(search.TimeControl{
+			MoveTime: time.Duration(params.moveTime) * time.Millisecond,
+		})
+	}
+
+	remaining, increment := params.wtime, params.winc
+	if !whiteToMove {
+		remaining, increment = params.btime, params.binc
+	}
+	if remaining <= 0 {
+		return nil
+	}
+	return search.NewTimeManager(search.TimeControl{
+		Remaining: time.Duration(remaining) * time.Millisecond,
+		Increment: time.Duration(increment) * time.Millisecond,
+		MovesToGo: params.movesToGo,
+	})
+}
+
+// startSearch runs the search in its own goroutine so "stop" and "isready"
+// are answered while it thinks
+func (s *Session) startSearch(params goParams) {
+	ctx, cancel := context.WithCancel(context.Background())
+	limits := search.Limits{
+		Depth:   params.depth,
+		Time:    params.timeManager(s.board.NextTurn),
+		MultiPV: s.multiPV,
+	}
+	done := make(chan struct{})
+	s.cancel = cancel
+	s.done = done
+
+	board := s.board
+	s.engine.OnIteration = func(info search.Info) {
+		s.sendInfo(info)
+	}
+
+	go func() {
+		defer close(done)
+		result := s.engine.Search(ctx, board, limits)
+
+		// In infinite mode the best move may only be sent after "stop"
+		if params.infinite {
+			<-ctx.Done()
+		}
+		if result.BestMove.IsNull() {
+			s.send("bestmove 0000")
+		} else {
+			s.send("bestmove %s", result.BestMove.UCI())
+		}
+	}()
+}
+
+// stopSearch cancels a running search and waits for its bestmove
+func (s *Session) stopSearch() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+	s.done = nil
+}
+
+// sendInfo reports an iteration with one "info" line per principal variation
+func (s *Session) sendInfo(info search.Info) {
+	milliseconds := info.Elapsed.Milliseconds()
+	nps := uint64(0)
+	if milliseconds > 0 {
+		nps = info.Nodes * 1000 / uint64(milliseconds)
+	}
+
+	for rank, line := range info.Lines {
+		moves := make([]string, len(line.PV))
+		for i, move := range line.PV {
+			moves[i] = move.UCI()
+		}
+		s.send("info depth %d multipv %d score %s nodes %d nps %d time %d pv %s",
+			info.Depth, rank+1, formatScore(line.Score), info.Nodes, nps, milliseconds, strings.Join(moves, " "))
+	}
+}
+
+// formatScore renders "cp <x>" or "mate <moves>", negative when being mated
+func formatScore(score int) string {
+	if score > components.MateThreshold {
+		return fmt.Sprintf("mate %d", (components.MateScore-score+1)/2)
+	}
+	if score < -components.MateThreshold {
+		return fmt.Sprintf("mate -%d", (components.MateScore+score)/2)
+	}
+	return fmt.Sprintf("cp %d", score)
+}
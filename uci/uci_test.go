@@ -0,0 +1,162 @@
+package uci
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/search"
+)
+
+// pipeSession drives Run over pipes, as a GUI would
+type pipeSession struct {
+	t     *testing.T
+	in    *io.PipeWriter
+	lines chan string
+	done  chan error
+}
+
+func newPipeSession(t *testing.T) *pipeSession {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	p := &pipeSession{t: t, in: inW, lines: make(chan string, 1000), done: make(chan error, 1)}
+
+	go func() {
+		err := Run(inR, outW)
+		outW.Close()
+		p.done <- err
+	}()
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			p.lines <- scanner.Text()
+		}
+		close(p.lines)
+	}()
+	return p
+}
+
+func (p *pipeSession) send(command string) {
+	if _, err := io.WriteString(p.in, command+"\n"); err != nil {
+		p.t.Fatalf("sending %q: %v", command, err)
+	}
+}
+
+// expect reads lines until one starts with prefix, returning it and the
+// lines before it
+func (p *pipeSession) expect(prefix string, timeout time.Duration) (string, []string) {
+	var before []string
+	deadline := time.After(timeout)
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				p.t.Fatalf("output closed waiting for %q", prefix)
+			}
+			if strings.HasPrefix(line, prefix) {
+				return line, before
+			}
+			before = append(before, line)
+		case <-deadline:
+			p.t.Fatalf("no %q within %s", prefix, timeout)
+		}
+	}
+}
+
+func (p *pipeSession) quit() {
+	p.send("quit")
+	select {
+	case err := <-p.done:
+		if err != nil {
+			p.t.Errorf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		p.t.Fatal("Run did not return after quit")
+	}
+}
+
+func TestSearchOverPipes(t *testing.T) {
+	p := newPipeSession(t)
+	p.send("uci")
+	p.expect("uciok", 5*time.Second)
+	p.send("isready")
+	p.expect("readyok", 5*time.Second)
+
+	p.send("position startpos moves e2e4 e7e5")
+	p.send("go depth 3")
+	line, before := p.expect("bestmove", 30*time.Second)
+
+	infos := 0
+	for _, info := range before {
+		if strings.HasPrefix(info, "info depth") {
+			infos++
+		}
+	}
+	if infos == 0 {
+		t.Errorf("no info depth lines before %q", line)
+	}
+
+	board, _ := components.ParseFEN(components.StartFEN)
+	for _, move := range []string{"e2e4", "e7e5"} {
+		next, err := board.ApplyUCIMove(move)
+		if err != nil {
+			t.Fatal(err)
+		}
+		board = *next
+	}
+	move := strings.Fields(line)[1]
+	if _, err := board.ApplyUCIMove(move); err != nil {
+		t.Errorf("bestmove %s is not legal: %v", move, err)
+	}
+	p.quit()
+}
+
+func TestStopInfiniteSearch(t *testing.T) {
+	p := newPipeSession(t)
+	p.send("position startpos")
+	p.send("go infinite")
+	time.Sleep(200 * time.Millisecond)
+
+	start := time.Now()
+	p.send("stop")
+	p.expect("bestmove", 2*time.Second)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("bestmove took %s after stop", elapsed)
+	}
+	p.quit()
+}
+
+// Hashes leave out the variant and Chess960, so changing either must not
+// leave entries behind that were stored under the old rules
+func TestRuleOptionsClearTT(t *testing.T) {
+	session := NewSession(io.Discard)
+	key := session.board.Hash()
+	stored := func() bool {
+		_, ok := session.engine.TT.Probe(key)
+		return ok
+	}
+
+	for _, option := range []string{
+		"setoption name UCI_Variant value atomic",
+		"setoption name UCI_Chess960 value true",
+		"setoption name UCI_Variant value standard",
+		"setoption name UCI_Chess960 value false",
+	} {
+		session.engine.TT.Store(search.Entry{Key: key, Depth: 1, Bound: search.BoundExact})
+		session.Handle(option)
+		if stored() {
+			t.Errorf("%q kept the table", option)
+		}
+	}
+
+	// Setting an option to its current value keeps the table
+	session.engine.TT.Store(search.Entry{Key: key, Depth: 1, Bound: search.BoundExact})
+	session.Handle("setoption name UCI_Variant value standard")
+	session.Handle("setoption name UCI_Chess960 value false")
+	if !stored() {
+		t.Error("unchanged options cleared the table")
+	}
+}
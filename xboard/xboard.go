@@ -0,0 +1,431 @@
+// Package xboard speaks the Chess Engine Communication Protocol (XBoard/CECP
+// version 2) on top of the same search and move parsing as the UCI front-end.
+package xboard
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LIAMBB/chess-compute/components"
+	"github.com/LIAMBB/chess-compute/search"
+	"github.com/LIAMBB/chess-compute/tablebase"
+)
+
+const EngineName = "chess-compute"
+
+// xboardVariants maps the XBoard names of the supported variants to their
+// rules. fischerandom is standard chess played from a Chess960 position.
+// Antichess is left out: XBoard's suicide scores a stalemate by the pieces
+// left and its giveaway allows castling, so under either name the GUI and
+// the engine would disagree on results or legal moves.
+var xboardVariants = map[string]components.Variant{
+	"normal":        components.Standard{},
+	"fischerandom":  components.Standard{},
+	"crazyhouse":    components.Crazyhouse{},
+	"atomic":        components.Atomic{},
+	"3check":        components.ThreeCheck{},
+	"kingofthehill": components.KingOfTheHill{},
+	"capablanca":    components.Capablanca{},
+}
+
+// Session holds the protocol state of one XBoard connection
+type Session struct {
+	out   io.Writer
+	outMu sync.Mutex
+
+	engine  *search.Engine
+	board   components.ChessBoard
+	history []components.ChessBoard
+
+	forceMode   bool
+	engineColor bool // side the engine plays, true = white
+	post        bool
+	chess960    bool   // variant fischerandom
+	variant     string // board variant name, empty for standard chess
+
+	// Time control, times in centiseconds as the protocol sends them
+	maxDepth        int
+	secondsPerMove  int
+	movesPerSession int
+	increment       int
+	engineTime      int
+
+	// Set while a search goroutine is running. mu guards board and history
+	// against the goroutine playing its move.
+	mu      sync.Mutex
+	cancel  context.CancelFunc
+	done    chan struct{}
+	discard bool
+}
+
+func NewSession(out io.Writer) *Session {
+	s := &Session{out: out, engine: search.NewEngine()}
+	s.newGame()
+	return s
+}
+
+// Run reads commands from in until "quit" or end of input, writing responses
+// to out
+func Run(in io.Reader, out io.Writer) error {
+	session := NewSession(out)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if !session.Handle(scanner.Text()) {
+			return nil
+		}
+	}
+	session.abortSearch()
+	return scanner.Err()
+}
+
+// Handle processes one command line and reports whether the session should
+// keep reading commands
+func (s *Session) Handle(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	args := fields[1:]
+
+	switch fields[0] {
+	case "xboard", "accepted", "rejected", "random", "hard", "easy", "computer", "otim":
+		// Nothing to do; the time manager only budgets the engine's own clock,
+		// so the opponent's time from otim is accepted and ignored
+	case "protover":
+		s.send(`feature myname="%s" ping=1 setboard=1 usermove=1 time=1 draw=0 sigint=0 sigterm=0 reuse=1 analyze=0 colors=0 san=0 egt="cctb" variants="normal,fischerandom,crazyhouse,atomic,3check,kingofthehill,capablanca" done=1`, EngineName)
+	case "new":
+		s.abortSearch()
+		s.newGame()
+	case "force":
+		s.abortSearch()
+		s.forceMode = true
+	case "go":
+		s.abortSearch()
+		s.forceMode = false
+		s.engineColor = s.board.NextTurn
+		s.startThinking()
+	case "?":
+		s.moveNow()
+	case "usermove":
+		if len(args) == 0 {
+			s.send("Error (missing move): usermove")
+			return true
+		}
+		s.abortSearch()
+		s.userMove(args[0])
+	case "variant":
+		s.abortSearch()
+		var variant components.Variant
+		if len(args) > 0 {
+			variant = xboardVariants[args[0]]
+		}
+		if variant == nil {
+			s.send("Error (unsupported variant): %s", strings.Join(args, " "))
+			return true
+		}
+		s.chess960 = args[0] == "fischerandom"
+		s.variant = ""
+		if _, standard := variant.(components.Standard); !standard {
+			s.variant = variant.Name()
+		}
+		// The variant follows "new", so the game starts from the variant's
+		// own start position, which may be on a wider board
+		s.board = variant.StartPosition()
+		s.board.Chess960 = s.chess960
+		s.history = nil
+	case "setboard":
+		s.abortSearch()
+		board, err := components.ParseFEN(strings.Join(args, " "))
+		if err != nil {
+			s.send("tellusererror Illegal position: %v", err)
+			return true
+		}
+		board.Chess960 = board.Chess960 || s.chess960
+		if s.variant != "" {
+			board.Variant = s.variant
+		}
+		s.board = board
+		s.history = nil
+	case "undo":
+		s.abortSearch()
+		s.undo(1)
+	case "remove":
+		s.abortSearch()
+		s.undo(2)
+	case "result":
+		s.abortSearch()
+		s.forceMode = true
+	case "post":
+		s.post = true
+	case "nopost":
+		s.post = false
+	case "ping":
+		s.send("pong %s", strings.Join(args, " "))
+	case "time":
+		if len(args) > 0 {
+			s.engineTime, _ = strconv.Atoi(args[0])
+		}
+	case "holding":
+		s.setHolding(args)
+	case "sd":
+		if len(args) > 0 {
+			s.maxDepth, _ = strconv.Atoi(args[0])
+		}
+	case "st":
+		if len(args) > 0 {
+			s.secondsPerMove, _ = strconv.Atoi(args[0])
+		}
+	case "level":
+		s.setLevel(args)
+	case "egtpath":
+		// egtpath cctb <dir> points at tables written by "tablebase generate"
+		if len(args) > 1 && args[0] == "cctb" {
+			s.abortSearch()
+			s.engine.Tablebases = tablebase.NewSet(strings.Join(args[1:], " "))
+		}
+	case "quit":
+		s.abortSearch()
+		return false
+	default:
+		s.send("Error (unknown command): %s", fields[0])
+	}
+	return true
+}
+
+func (s *Session) send(format string, args ...interface{}) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, format+"\n", args...)
+}
+
+func (s *Session) newGame() {
+	s.board, _ = components.ParseFEN(components.StartFEN)
+	s.history = nil
+	s.chess960 = false
+	s.variant = ""
+	s.forceMode = false
+	s.engineColor = false
+	s.maxDepth = 0
+	s.secondsPerMove = 0
+	s.engine.TT.Clear()
+}
+
+// setLevel handles "level MPS BASE INC". BASE is not needed since the GUI
+// reports the remaining time with "time" before every move.
+func (s *Session) setLevel(args []string) {
+	if len(args) < 3 {
+		s.send("Error (bad level): level %s", strings.Join(args, " "))
+		return
+	}
+	s.movesPerSession, _ = strconv.Atoi(args[0])
+	s.increment, _ = strconv.Atoi(args[2])
+	s.secondsPerMove = 0
+}
+
+// setHolding handles "holding [WHITE] [BLACK]", which the GUI sends in
+// crazyhouse after every move. The pockets it lists replace the tracked ones;
+// a running search keeps the board it started from.
+func (s *Session) setHolding(args []string) {
+	if len(args) < 2 || !strings.HasPrefix(args[0], "[") || !strings.HasPrefix(args[1], "[") {
+		s.send("Error (bad holding): holding %s", strings.Join(args, " "))
+		return
+	}
+	white := strings.Trim(args[0], "[]")
+	black := strings.ToLower(strings.Trim(args[1], "[]"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.board.Variant != (components.Crazyhouse{}).Name() {
+		s.send("Error (holding outside crazyhouse): holding %s", strings.Join(args, " "))
+		return
+	}
+	if err := s.board.SetHoldings(white + black); err != nil {
+		s.send("Error (bad holding): %v", err)
+	}
+}
+
+func (s *Session) userMove(text string) {
+	next, err := parseMove(&s.board, text)
+	if err != nil {
+		s.send("Illegal move: %s", text)
+		return
+	}
+	s.history = append(s.history, s.board)
+	s.board = *next
+	if s.reportResult() {
+		return
+	}
+
+	if !s.forceMode && s.board.NextTurn == s.engineColor {
+		s.startThinking()
+	}
+}
+
+func (s *Session) undo(plies int) {
+	for i := 0; i < plies && len(s.history) > 0; i++ {
+		s.board = s.history[len(s.history)-1]
+		s.history = s.history[:len(s.history)-1]
+	}
+}
+
+// reportResult announces the end of the game under the board's variant and
+// reports whether the game is over
+func (s *Session) reportResult() bool {
+	variant := components.VariantOf(&s.board)
+	moves := components.LegalMoves(variant, &s.board)
+	result := variant.Result(&s.board, moves)
+	switch {
+	case result == components.Ongoing:
+		return false
+	case result == components.Draw && len(moves) == 0:
+		s.send("%s {Stalemate}", result)
+	case result == components.Draw:
+		s.send("%s {Draw}", result)
+	case len(moves) == 0 && variant.InCheck(&s.board):
+		if result == components.WhiteWins {
+			s.send("%s {White mates}", result)
+		} else {
+			s.send("%s {Black mates}", result)
+		}
+	case result == components.WhiteWins:
+		s.send("%s {White wins}", result)
+	default:
+		s.send("%s {Black wins}", result)
+	}
+	return true
+}
+
+// timeManager returns the time limits for the next move, or nil for none
+func (s *Session) timeManager() *search.TimeManager {
+	if s.secondsPerMove > 0 {
+		return search.NewTimeManager(search.TimeControl{
+			MoveTime: time.Duration(s.secondsPerMove) * time.Second,
+		})
+	}
+	if s.engineTime <= 0 {
+		return nil
+	}
+
+	movesToGo := 0
+	if s.movesPerSession > 0 {
+		movesPlayed := len(s.history) / 2
+		movesToGo = s.movesPerSession - movesPlayed%s.movesPerSession
+	}
+	return search.NewTimeManager(search.TimeControl{
+		Remaining: time.Duration(s.engineTime) * 10 * time.Millisecond,
+		Increment: time.Duration(s.increment) * time.Second,
+		MovesToGo: movesToGo,
+	})
+}
+
+// startThinking searches for the engine's move in its own goroutine, so
+// commands such as "?" and "force" are handled while it thinks
+func (s *Session) startThinking() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	s.cancel = cancel
+	s.done = done
+	s.discard = false
+
+	board := s.board
+	post := s.post
+	limits := search.Limits{Depth: s.maxDepth, Time: s.timeManager()}
+	s.engine.OnIteration = func(info search.Info) {
+		if post {
+			s.sendThinking(info)
+		}
+	}
+
+	go func() {
+		defer close(done)
+		result := s.engine.Search(ctx, board, limits)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.discard || result.BestMove.IsNull() {
+			return
+		}
+		next, err := board.ApplyUCIMove(result.BestMove.UCI())
+		if err != nil {
+			s.send("Error (engine move): %v", err)
+			return
+		}
+		s.history = append(s.history, board)
+		s.board = *next
+		s.send("move %s", moveText(&board, result.BestMove))
+		s.reportResult()
+	}()
+}
+
+// moveNow stops the search and plays the best move found so far
+func (s *Session) moveNow() {
+	s.finishSearch(false)
+}
+
+// abortSearch stops the search without playing a move
+func (s *Session) abortSearch() {
+	s.finishSearch(true)
+}
+
+func (s *Session) finishSearch(discard bool) {
+	if s.cancel == nil {
+		return
+	}
+	s.mu.Lock()
+	s.discard = discard
+	s.mu.Unlock()
+
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+	s.done = nil
+}
+
+// sendThinking prints "ply score time nodes pv" with time in centiseconds.
+// Mate scores use the 100000+moves convention XBoard understands.
+func (s *Session) sendThinking(info search.Info) {
+	score := info.Score
+	if score > components.MateThreshold {
+		score = 100000 + (components.MateScore-score+1)/2
+	} else if score < -components.MateThreshold {
+		score = -100000 - (components.MateScore+score)/2
+	}
+
+	moves := make([]string, len(info.PV))
+	for i, move := range info.PV {
+		moves[i] = move.UCI()
+	}
+	s.send("%d %d %d %d %s", info.Depth, score, info.Elapsed.Milliseconds()/10, info.Nodes, strings.Join(moves, " "))
+}
+
+// moveText renders a move for the GUI. Chess960 castling is sent as O-O or
+// O-O-O, as XBoard expects in fischerandom.
+func moveText(board *components.ChessBoard, move components.Move) string {
+	if board.Chess960 && board.IsCastling(move) {
+		if move.To.X > move.From.X {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+	return move.UCI()
+}
+
+// parseMove plays a move sent by the GUI in coordinate notation, or as O-O
+// or O-O-O
+func parseMove(board *components.ChessBoard, text string) (*components.ChessBoard, error) {
+	if text == "O-O" || text == "O-O-O" {
+		for _, child := range components.LegalMoves(components.VariantOf(board), board) {
+			if board.IsCastling(child.LastMove) && (child.LastMove.To.X > child.LastMove.From.X) == (text == "O-O") {
+				return child, nil
+			}
+		}
+		return nil, fmt.Errorf("illegal move %q", text)
+	}
+	return board.ApplyUCIMove(text)
+}